@@ -1,50 +1,244 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/sokolawesome/tunecli/internal/config"
+	"github.com/sokolawesome/tunecli/internal/headless"
 	"github.com/sokolawesome/tunecli/internal/logview"
 	"github.com/sokolawesome/tunecli/internal/mpris"
+	"github.com/sokolawesome/tunecli/internal/notify"
 	"github.com/sokolawesome/tunecli/internal/player"
+	"github.com/sokolawesome/tunecli/internal/scanner"
+	"github.com/sokolawesome/tunecli/internal/status"
 	"github.com/sokolawesome/tunecli/internal/ui"
 )
 
+var version = "dev"
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "status":
+			printStatus(os.Args[2:])
+			return
+		case "scan":
+			printScan(os.Args[2:])
+			return
+		}
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] [file|url ...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s status [-json]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s scan [-config path] [-dir path] [-probe] [-follow-symlinks]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Files and URLs given as arguments are played immediately, in order,")
+		fmt.Fprintln(flag.CommandLine.Output(), "instead of scanning the configured music directories.")
+		fmt.Fprintln(flag.CommandLine.Output(), "The status command prints the state of an already running instance.")
+		fmt.Fprintln(flag.CommandLine.Output(), "The scan command prints the music files tunecli finds as JSON.")
+		flag.PrintDefaults()
+	}
+	configPath := flag.String("config", "", "path to the config file (defaults to the user config directory)")
+	headlessMode := flag.Bool("headless", false, "run without the terminal UI, controlled only via MPRIS")
+	verbose := flag.Bool("verbose", false, "show mpv's error output in the log")
+	printVersion := flag.Bool("version", false, "print the version and exit")
+	flag.Parse()
+
+	if *printVersion {
+		fmt.Printf("tunecli %s\n", version)
+		return
+	}
+
+	tracks := flag.Args()
+	for _, track := range tracks {
+		if strings.Contains(track, "://") {
+			continue
+		}
+		if _, err := os.Stat(track); err != nil {
+			log.Fatalf("error: %s", err)
+		}
+	}
+
 	logChan := make(chan string, 20)
-	logger := logview.NewLogWriter(logChan)
-	log.SetOutput(logger)
+	if !*headlessMode {
+		logger := logview.NewLogWriter(logChan)
+		log.SetOutput(logger)
+	}
 	log.SetFlags(0)
 	log.Print("tunecli starting...")
 
-	config, err := config.LoadConfig()
+	var cfg *config.Config
+	var err error
+	if *configPath != "" {
+		cfg, err = config.LoadConfigFrom(*configPath)
+	} else {
+		cfg, err = config.LoadConfig()
+	}
 	if err != nil {
 		log.Fatalf("error: %s", err)
 	}
 
+	equalizer, err := cfg.EqualizerBands()
+	if err != nil {
+		log.Printf("error: %s", err)
+	}
+
+	audioFilter, err := cfg.AudioFilterChain(cfg.AudioFilter)
+	if err != nil {
+		log.Printf("error: %s", err)
+	}
+
 	cmdChan := make(chan string, 1)
 
-	player, err := player.NewPlayer()
+	player, err := player.NewPlayer(player.Options{
+		ReplayGain:  cfg.ReplayGain,
+		AudioDevice: cfg.AudioDevice,
+		Equalizer:   equalizer,
+		Gapless:     cfg.Gapless,
+		Crossfade:   cfg.Crossfade,
+		Fade:        time.Duration(cfg.FadeMs) * time.Millisecond,
+		AudioFilter: audioFilter,
+		Verbose:     *verbose || cfg.Verbose,
+		Ytdl:        cfg.Ytdl,
+		ExtraArgs:   cfg.MpvArgs,
+	})
 	if err != nil {
 		log.Fatalf("error: %s", err)
 	}
 	defer player.Close()
 
-	server, err := mpris.NewMprisServer(cmdChan)
+	if err := player.SetVolume(float64(cfg.Volume)); err != nil {
+		log.Printf("Failed to set startup volume: %v", err)
+	}
+
+	server, err := mpris.NewMprisServer(cmdChan, version)
 	if err != nil {
 		log.Fatalf("error: %s", err)
 	}
 	defer server.Close()
 
-	model, err := ui.NewModel(player, config, cmdChan, logChan, server)
+	var notifier *notify.Notifier
+	if cfg.Notifications {
+		if notifier, err = notify.NewNotifier(); err != nil {
+			log.Printf("Failed to enable notifications: %v", err)
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	if *headlessMode {
+		headless.NewDaemon(player, server, notifier, cmdChan).Run(tracks, signals)
+		return
+	}
+
+	model, err := ui.NewModel(player, cfg, cmdChan, logChan, server, notifier, tracks)
 	if err != nil {
 		log.Fatalf("error: %s", err)
 	}
 
-	program := tea.NewProgram(model, tea.WithAltScreen())
+	program := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
 
-	if _, err := program.Run(); err != nil {
-		log.Fatalf("error: %s", err)
+	go func() {
+		sig := <-signals
+		log.Printf("Received %s, shutting down", sig)
+		program.Quit()
+	}()
+
+	_, err = program.Run()
+	log.SetOutput(os.Stderr)
+	model.SaveState()
+	model.Close()
+	if err != nil {
+		log.Printf("error: %s", err)
+	}
+}
+
+func printStatus(args []string) {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print the status as JSON")
+	flags.Parse(args)
+
+	paths, err := player.SocketPaths()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+
+	current, err := status.QueryAny(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+
+	if !*jsonOutput {
+		fmt.Println(current)
+		return
+	}
+
+	output, err := json.Marshal(current)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(output))
+}
+
+func printScan(args []string) {
+	flags := flag.NewFlagSet("scan", flag.ExitOnError)
+	configPath := flags.String("config", "", "path to the config file (defaults to the user config directory)")
+	dir := flags.String("dir", "", "scan this directory instead of the configured music directories")
+	probe := flags.Bool("probe", false, "probe each file with mpv for its duration and codec")
+	followSymlinks := flags.Bool("follow-symlinks", false, "follow symlinked directories")
+	flags.Parse(args)
+
+	dirs := []scanner.Directory{{Path: *dir, Recursive: true}}
+	options := scanner.Options{
+		FollowSymlinks:  *followSymlinks,
+		ExcludePatterns: scanner.DefaultIgnore,
+		ProbeDurations:  *probe,
+	}
+	if *dir == "" {
+		var cfg *config.Config
+		var err error
+		if *configPath != "" {
+			cfg, err = config.LoadConfigFrom(*configPath)
+		} else {
+			cfg, err = config.LoadConfig()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		dirs = cfg.ScanDirectories()
+		options = cfg.ScanOptions()
+		options.FollowSymlinks = options.FollowSymlinks || *followSymlinks
+		options.ProbeDurations = options.ProbeDurations || *probe
+	}
+
+	files, err := scanner.ScanDirectories(dirs, options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+	if files == nil {
+		files = []scanner.MusicFile{}
+	}
+
+	output, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
 	}
+	fmt.Println(string(output))
 }
@@ -0,0 +1,90 @@
+package alarm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Alarm struct {
+	Hour   int
+	Minute int
+	Days   []time.Weekday
+	Source string
+	Volume float64
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+func New(clock string, days []string, source string, volume float64) (Alarm, error) {
+	at, err := time.Parse("15:04", clock)
+	if err != nil {
+		return Alarm{}, fmt.Errorf("invalid alarm time %q: %s", clock, err)
+	}
+	if source == "" {
+		return Alarm{}, fmt.Errorf("alarm at %s has no source", clock)
+	}
+	if volume < 0 || volume > 100 {
+		return Alarm{}, fmt.Errorf("alarm at %s has invalid volume %g", clock, volume)
+	}
+
+	alarm := Alarm{Hour: at.Hour(), Minute: at.Minute(), Source: source, Volume: volume}
+	for _, day := range days {
+		weekday, ok := parseWeekday(day)
+		if !ok {
+			return Alarm{}, fmt.Errorf("alarm at %s has invalid day %q", clock, day)
+		}
+		alarm.Days = append(alarm.Days, weekday)
+	}
+
+	return alarm, nil
+}
+
+func parseWeekday(day string) (time.Weekday, bool) {
+	day = strings.ToLower(strings.TrimSpace(day))
+	if len(day) < 3 {
+		return 0, false
+	}
+
+	for name, weekday := range weekdays {
+		if strings.HasPrefix(name, day) {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
+
+func (alarm Alarm) Next(now time.Time) time.Time {
+	for offset := range 8 {
+		day := now.AddDate(0, 0, offset)
+		at := time.Date(day.Year(), day.Month(), day.Day(), alarm.Hour, alarm.Minute, 0, 0, now.Location())
+		if at.After(now) && (len(alarm.Days) == 0 || slices.Contains(alarm.Days, at.Weekday())) {
+			return at
+		}
+	}
+
+	return time.Time{}
+}
+
+func Next(alarms []Alarm, now time.Time) (Alarm, time.Time, bool) {
+	var next Alarm
+	var nextAt time.Time
+
+	for _, alarm := range alarms {
+		at := alarm.Next(now)
+		if !at.IsZero() && (nextAt.IsZero() || at.Before(nextAt)) {
+			next, nextAt = alarm, at
+		}
+	}
+
+	return next, nextAt, !nextAt.IsZero()
+}
@@ -2,41 +2,161 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 
+	"github.com/sokolawesome/tunecli/internal/scanner"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	MusicDirs []string   `yaml:"music_dirs"`
-	Stations  []Stations `yaml:"stations"`
+	Version        int        `yaml:"version"`
+	MusicDirs      []MusicDir `yaml:"music_dirs"`
+	Stations       []Stations `yaml:"stations"`
+	Theme          Theme      `yaml:"theme,omitempty"`
+	ConfirmQuit    bool       `yaml:"confirm_quit"`
+	ReplayGain     string     `yaml:"replaygain,omitempty"`
+	AudioDevice    string     `yaml:"audio_device,omitempty"`
+	Equalizer      string     `yaml:"equalizer,omitempty"`
+	Resume         bool       `yaml:"resume_playback"`
+	Gapless        bool       `yaml:"gapless"`
+	Crossfade      float64    `yaml:"crossfade_seconds,omitempty"`
+	FadeMs         int        `yaml:"fade_ms,omitempty"`
+	RestoreSession bool       `yaml:"restore_session"`
+	History        bool       `yaml:"history"`
+	HistorySize    int        `yaml:"history_size,omitempty"`
+	FollowSymlinks bool       `yaml:"follow_symlinks"`
+	IncludeHidden  bool       `yaml:"include_hidden,omitempty"`
+	Exclude        []string   `yaml:"exclude,omitempty"`
+	Ignore         []string   `yaml:"ignore,omitempty"`
+	ProbeDurations bool       `yaml:"probe_durations"`
+	WatchDirs      bool       `yaml:"watch_dirs"`
+	Verbose        bool       `yaml:"verbose,omitempty"`
+	Alarms         []Alarm    `yaml:"alarms,omitempty"`
+	Notifications  bool       `yaml:"notifications"`
+	Volume         int        `yaml:"volume"`
+	MpvArgs        []string   `yaml:"mpv_args,omitempty"`
+	Ytdl           bool       `yaml:"ytdl"`
+
+	EqualizerPresets map[string][]float64 `yaml:"equalizer_presets,omitempty"`
+	AudioFilter      string               `yaml:"audio_filter,omitempty"`
+	AudioFilters     map[string]string    `yaml:"audio_filters,omitempty"`
+
+	Path           string `yaml:"-"`
+	rawMusicDirs   []MusicDir
+	rawStationURLs map[string]string
+}
+
+type MusicDir struct {
+	Path      string `yaml:"path"`
+	Recursive bool   `yaml:"recursive"`
+}
+
+type Theme struct {
+	Name     string `yaml:"name,omitempty"`
+	Selected string `yaml:"selected,omitempty"`
+	Border   string `yaml:"border,omitempty"`
+	Accent   string `yaml:"accent,omitempty"`
+	Playing  string `yaml:"playing,omitempty"`
+	Paused   string `yaml:"paused,omitempty"`
+	Stopped  string `yaml:"stopped,omitempty"`
+}
+
+type Alarm struct {
+	Time   string   `yaml:"time"`
+	Source string   `yaml:"source"`
+	Volume float64  `yaml:"volume,omitempty"`
+	Days   []string `yaml:"days,omitempty"`
 }
 
 type Stations struct {
-	Name string `yaml:"name"`
-	Url  string `yaml:"url"`
+	Name     string `yaml:"name"`
+	Url      string `yaml:"url"`
+	Homepage string `yaml:"homepage,omitempty"`
+	Favorite bool   `yaml:"favorite,omitempty"`
+}
+
+func (dir *MusicDir) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		dir.Recursive = true
+		return node.Decode(&dir.Path)
+	}
+
+	type plain MusicDir
+	entry := plain{Recursive: true}
+	if err := node.Decode(&entry); err != nil {
+		return err
+	}
+	if entry.Path == "" {
+		return fmt.Errorf("line %d: music dir is missing a path", node.Line)
+	}
+
+	*dir = MusicDir(entry)
+	return nil
+}
+
+func (dir MusicDir) MarshalYAML() (any, error) {
+	if dir.Recursive {
+		return dir.Path, nil
+	}
+
+	type plain MusicDir
+	return plain(dir), nil
+}
+
+var DefaultEqualizerPresets = map[string][]float64{
+	"flat":  {0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	"bass":  {6, 5, 4, 2, 0, 0, 0, 0, 0, 0},
+	"vocal": {-2, -2, -1, 0, 2, 4, 4, 2, 0, -1},
+}
+
+const CurrentVersion = 1
+
+const DefaultVolume = 100
+
+// reservedMpvOptions would break the IPC connection or load config that can
+// override it, so mpv_args may not set them.
+var reservedMpvOptions = []string{"input-ipc-server", "idle", "include", "profile", "config", "config-dir", "no-config"}
+
+var migrations = map[int]func(config *Config){}
+
+var DefaultAudioFilters = map[string]string{
+	"loudnorm": "lavfi=[loudnorm]",
+	"bass":     "lavfi=[bass=g=6]",
+	"treble":   "lavfi=[treble=g=4]",
+}
+
+func DefaultPath() (string, error) {
+	cfgDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to load user config directory: %s", err)
+	}
+
+	return filepath.Join(cfgDir, "tunecli", "config.yaml"), nil
 }
 
 func LoadConfig() (*Config, error) {
-	cfgPath, err := os.UserConfigDir()
+	cfgPath, err := DefaultPath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load user config directory: %s", err)
+		return nil, err
 	}
 
-	cfgPath = filepath.Join(cfgPath + "/tunecli/config.yaml")
+	return LoadConfigFrom(cfgPath)
+}
 
-	_, err = os.Stat(cfgPath)
+func LoadConfigFrom(cfgPath string) (*Config, error) {
+	_, err := os.Stat(cfgPath)
 	if err != nil {
-		if os.IsNotExist(err) {
-			cfg, err := saveDefaultConfig(cfgPath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create default config: %s", err)
-			}
-			return cfg, nil
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to load config file: %s", err)
+		}
+		if err := saveDefaultConfig(cfgPath); err != nil {
+			return nil, fmt.Errorf("failed to create default config: %s", err)
 		}
-		return nil, fmt.Errorf("failed to load config file: %s", err)
 	}
 
 	cfg, err := os.ReadFile(cfgPath)
@@ -44,29 +164,329 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %s", err)
 	}
 
-	var config Config
+	config := Config{
+		Gapless: true,
+		Volume:  DefaultVolume,
+		Ytdl:    true,
+	}
 	err = yaml.Unmarshal(cfg, &config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %s", err)
 	}
 
+	config.Path = cfgPath
+
+	if err := config.migrate(cfg, CurrentVersion); err != nil {
+		return nil, err
+	}
+	config.rawMusicDirs = slices.Clone(config.MusicDirs)
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user home directory: %s", err)
 	}
 
+	baseDir, err := filepath.Abs(filepath.Dir(cfgPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config directory: %s", err)
+	}
+
 	for i, dir := range config.MusicDirs {
-		if strings.HasPrefix(dir, "~/") {
-			config.MusicDirs[i] = filepath.Join(home, dir[2:])
+		config.MusicDirs[i].Path = resolveDir(dir.Path, home, baseDir)
+	}
+
+	config.rawStationURLs = make(map[string]string)
+	for i, station := range config.Stations {
+		expanded := expandEnv(station.Url, home)
+		if expanded != station.Url {
+			config.rawStationURLs[expanded] = station.Url
+			config.Stations[i].Url = expanded
 		}
 	}
 
 	return &config, nil
 }
 
-func saveDefaultConfig(cfgPath string) (*Config, error) {
+func (config *Config) validate() error {
+	if config.Volume < 0 || config.Volume > 100 {
+		return fmt.Errorf("invalid volume %d: must be between 0 and 100", config.Volume)
+	}
+
+	for _, arg := range config.MpvArgs {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if slices.Contains(reservedMpvOptions, name) || slices.Contains(reservedMpvOptions, strings.TrimPrefix(name, "no-")) {
+			return fmt.Errorf("invalid mpv argument %q: tunecli manages this option", arg)
+		}
+	}
+
+	return nil
+}
+
+func (config *Config) migrate(original []byte, target int) error {
+	if config.Version == 0 {
+		config.Version = 1
+	}
+	if config.Version > target {
+		return fmt.Errorf("config version %d is newer than the supported version %d", config.Version, target)
+	}
+	if config.Version == target {
+		return nil
+	}
+
+	for config.Version < target {
+		if migration, ok := migrations[config.Version]; ok {
+			migration(config)
+		}
+		config.Version++
+	}
+
+	if err := os.WriteFile(config.Path+".bak", original, 0600); err != nil {
+		return fmt.Errorf("failed to back up config before migration: %s", err)
+	}
+
+	if err := config.Save(config.Path); err != nil {
+		return fmt.Errorf("failed to save migrated config: %s", err)
+	}
+
+	return nil
+}
+
+// resolveDir keeps absolute paths, expands a leading ~ to the home directory,
+// then expands environment variables and resolves anything still relative
+// against the directory containing the config file.
+func resolveDir(dir string, home string, baseDir string) string {
+	switch {
+	case filepath.IsAbs(dir):
+		return dir
+	case dir == "~" || strings.HasPrefix(dir, "~/"):
+		return filepath.Join(home, dir[1:])
+	}
+
+	dir = expandEnv(dir, home)
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+
+	return filepath.Join(baseDir, dir)
+}
+
+// expandEnv replaces $VAR and ${VAR} references. XDG_MUSIC_DIR falls back to
+// the user-dirs.dirs entry, and unset variables are left untouched.
+func expandEnv(value string, home string) string {
+	var expanded strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' {
+			expanded.WriteByte(value[i])
+			continue
+		}
+
+		name, width := envReference(value[i+1:])
+		resolved, ok := lookupEnv(name, home)
+		if name == "" || !ok {
+			expanded.WriteByte('$')
+			continue
+		}
+
+		expanded.WriteString(resolved)
+		i += width
+	}
+
+	return expanded.String()
+}
+
+// envReference returns the variable name at the start of text, which follows
+// a $, and how many bytes the reference spans.
+func envReference(text string) (string, int) {
+	if strings.HasPrefix(text, "{") {
+		end := strings.IndexByte(text, '}')
+		if end < 0 {
+			return "", 0
+		}
+		return text[1:end], end + 1
+	}
+
+	end := 0
+	for end < len(text) && (text[end] == '_' || isAlphaNumeric(text[end])) {
+		end++
+	}
+	return text[:end], end
+}
+
+func isAlphaNumeric(char byte) bool {
+	return 'a' <= char && char <= 'z' || 'A' <= char && char <= 'Z' || '0' <= char && char <= '9'
+}
+
+func lookupEnv(name string, home string) (string, bool) {
+	if env, ok := os.LookupEnv(name); ok {
+		return env, true
+	}
+	if name == "XDG_MUSIC_DIR" {
+		return xdgMusicDir(home), true
+	}
+	return "", false
+}
+
+func xdgMusicDir(home string) string {
+	fallback := filepath.Join(home, "Music")
+
+	cfgDir, err := os.UserConfigDir()
+	if err != nil {
+		return fallback
+	}
+	data, err := os.ReadFile(filepath.Join(cfgDir, "user-dirs.dirs"))
+	if err != nil {
+		return fallback
+	}
+
+	for line := range strings.Lines(string(data)) {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "XDG_MUSIC_DIR=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		if rest, ok := strings.CutPrefix(value, "$HOME"); ok {
+			return home + rest
+		}
+		return value
+	}
+
+	return fallback
+}
+
+func (config *Config) ScanDirectories() []scanner.Directory {
+	dirs := make([]scanner.Directory, len(config.MusicDirs))
+	for i, dir := range config.MusicDirs {
+		dirs[i] = scanner.Directory{Path: dir.Path, Recursive: dir.Recursive}
+	}
+	return dirs
+}
+
+func (config *Config) ScanOptions() scanner.Options {
+	ignore := config.Ignore
+	if ignore == nil {
+		ignore = scanner.DefaultIgnore
+	}
+
+	return scanner.Options{
+		FollowSymlinks:  config.FollowSymlinks,
+		IncludeHidden:   config.IncludeHidden,
+		ExcludePatterns: slices.Concat(ignore, config.Exclude),
+		ProbeDurations:  config.ProbeDurations,
+	}
+}
+
+func (config *Config) EqualizerBands() ([]float64, error) {
+	if config.Equalizer == "" {
+		return nil, nil
+	}
+
+	if bands, ok := config.EqualizerPresets[config.Equalizer]; ok {
+		return bands, nil
+	}
+	if bands, ok := DefaultEqualizerPresets[config.Equalizer]; ok {
+		return bands, nil
+	}
+
+	return nil, fmt.Errorf("unknown equalizer preset: %s", config.Equalizer)
+}
+
+func (config *Config) AudioFilterNames() []string {
+	names := slices.Collect(maps.Keys(DefaultAudioFilters))
+	for name := range config.AudioFilters {
+		if _, ok := DefaultAudioFilters[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+func (config *Config) AudioFilterChain(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+
+	if filter, ok := config.AudioFilters[name]; ok {
+		return filter, nil
+	}
+	if filter, ok := DefaultAudioFilters[name]; ok {
+		return filter, nil
+	}
+
+	return "", fmt.Errorf("unknown audio filter preset: %s", name)
+}
+
+var saveMutex sync.Mutex
+
+func (config *Config) Save(cfgPath string) error {
+	saveMutex.Lock()
+	defer saveMutex.Unlock()
+
+	saved := *config
+	if saved.rawMusicDirs != nil {
+		saved.MusicDirs = saved.rawMusicDirs
+	}
+	if len(saved.rawStationURLs) > 0 {
+		saved.Stations = slices.Clone(saved.Stations)
+		for i, station := range saved.Stations {
+			if raw, ok := saved.rawStationURLs[station.Url]; ok {
+				saved.Stations[i].Url = raw
+			}
+		}
+	}
+
+	data, err := yaml.Marshal(&saved)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %s", err)
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(cfgPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(cfgPath), ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to create temp config file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp config file: %s", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set config file mode: %s", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp config file: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp config file: %s", err)
+	}
+
+	if err := os.Rename(tmp.Name(), cfgPath); err != nil {
+		return fmt.Errorf("failed to replace config file: %s", err)
+	}
+
+	return nil
+}
+
+func saveDefaultConfig(cfgPath string) error {
 	config := &Config{
-		MusicDirs: []string{"~/Music"},
+		Version:   CurrentVersion,
+		Gapless:   true,
+		Volume:    DefaultVolume,
+		Ytdl:      true,
+		MusicDirs: []MusicDir{{Path: "~/Music", Recursive: true}},
 		Stations: []Stations{
 			{
 				Name: "Record Lo-Fi",
@@ -78,19 +498,9 @@ func saveDefaultConfig(cfgPath string) (*Config, error) {
 		},
 	}
 
-	data, err := yaml.Marshal(config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal config: %s", err)
-	}
-
-	if err = os.MkdirAll(filepath.Dir(cfgPath), 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %s", err)
-	}
-
-	err = os.WriteFile(cfgPath, data, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write config file: %s", err)
+	if err := os.MkdirAll(filepath.Dir(cfgPath), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %s", err)
 	}
 
-	return config, nil
+	return config.Save(cfgPath)
 }
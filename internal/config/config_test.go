@@ -0,0 +1,275 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMusicDirUnmarshal(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  MusicDir
+	}{
+		{"string", `"~/Music"`, MusicDir{Path: "~/Music", Recursive: true}},
+		{"object", "path: ~/Music\nrecursive: false", MusicDir{Path: "~/Music", Recursive: false}},
+		{"object without recursive", "path: ~/Music", MusicDir{Path: "~/Music", Recursive: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var dir MusicDir
+			if err := yaml.Unmarshal([]byte(test.input), &dir); err != nil {
+				t.Fatalf("unmarshal %q: %v", test.input, err)
+			}
+			if dir != test.want {
+				t.Errorf("unmarshal %q = %+v, want %+v", test.input, dir, test.want)
+			}
+		})
+	}
+}
+
+func TestMusicDirUnmarshalMissingPath(t *testing.T) {
+	var dir MusicDir
+	err := yaml.Unmarshal([]byte("recursive: false"), &dir)
+	if err == nil || !strings.Contains(err.Error(), "missing a path") {
+		t.Fatalf("unmarshal without path: got error %v, want missing path error", err)
+	}
+}
+
+func TestMusicDirRoundTrip(t *testing.T) {
+	dirs := []MusicDir{
+		{Path: "~/Music", Recursive: true},
+		{Path: "/srv/audio/inbox", Recursive: false},
+	}
+
+	data, err := yaml.Marshal(dirs)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(data), "- ~/Music\n") {
+		t.Errorf("recursive dir is not written as a plain string:\n%s", data)
+	}
+
+	var decoded []MusicDir
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(decoded) != len(dirs) {
+		t.Fatalf("round trip gave %d dirs, want %d", len(decoded), len(dirs))
+	}
+	for i := range dirs {
+		if decoded[i] != dirs[i] {
+			t.Errorf("dir %d = %+v, want %+v", i, decoded[i], dirs[i])
+		}
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigRejectsNewerVersion(t *testing.T) {
+	path := writeConfig(t, "version: 99\nmusic_dirs: [/music]\n")
+
+	if _, err := LoadConfigFrom(path); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("load of a newer config: got error %v, want version error", err)
+	}
+}
+
+func TestMigrateBacksUpAndRewrites(t *testing.T) {
+	original := "version: 1\nmusic_dirs:\n  - ~/Music\nvolume: 100\n"
+	path := writeConfig(t, original)
+
+	migrations[1] = func(config *Config) {
+		config.Volume = 50
+	}
+	t.Cleanup(func() { delete(migrations, 1) })
+
+	var config Config
+	if err := yaml.Unmarshal([]byte(original), &config); err != nil {
+		t.Fatal(err)
+	}
+	config.Path = path
+
+	if err := config.migrate([]byte(original), 2); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	backup, err := os.ReadFile(path + ".bak")
+	if err != nil {
+		t.Fatalf("read backup: %v", err)
+	}
+	if string(backup) != original {
+		t.Errorf("backup = %q, want %q", backup, original)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var migrated Config
+	if err := yaml.Unmarshal(data, &migrated); err != nil {
+		t.Fatalf("unmarshal migrated config: %v", err)
+	}
+	if migrated.Version != 2 || migrated.Volume != 50 {
+		t.Errorf("migrated config has version %d and volume %d, want 2 and 50", migrated.Version, migrated.Volume)
+	}
+	if len(migrated.MusicDirs) != 1 || migrated.MusicDirs[0].Path != "~/Music" {
+		t.Errorf("migrated music_dirs = %+v, want ~/Music kept as written", migrated.MusicDirs)
+	}
+}
+
+func TestValidateMpvArgs(t *testing.T) {
+	tests := []struct {
+		arg   string
+		valid bool
+	}{
+		{"--cache=yes", true},
+		{"--ytdl-format=bestaudio", true},
+		{"--input-ipc-server=/tmp/other.sock", false},
+		{"-input-ipc-server=/tmp/other.sock", false},
+		{"--idle=no", false},
+		{"--no-idle", false},
+		{"--include=/tmp/mpv.conf", false},
+		{"--profile=custom", false},
+		{"--config-dir=/tmp", false},
+		{"--no-config", false},
+	}
+
+	for _, test := range tests {
+		config := Config{Volume: DefaultVolume, MpvArgs: []string{test.arg}}
+		if err := config.validate(); (err == nil) != test.valid {
+			t.Errorf("validate %q: got error %v, want valid %t", test.arg, err, test.valid)
+		}
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("TUNECLI_HOST", "radio.example")
+	t.Setenv("XDG_MUSIC_DIR", "/srv/music")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"https://$TUNECLI_HOST/live", "https://radio.example/live"},
+		{"https://${TUNECLI_HOST}/live", "https://radio.example/live"},
+		{"$XDG_MUSIC_DIR/rock", "/srv/music/rock"},
+		{"https://radio.example/live?a=$b", "https://radio.example/live?a=$b"},
+		{"$TUNECLI_UNSET/rock", "$TUNECLI_UNSET/rock"},
+		{"${TUNECLI_UNSET}/rock", "${TUNECLI_UNSET}/rock"},
+		{"price$", "price$"},
+		{"${unterminated", "${unterminated"},
+	}
+
+	for _, test := range tests {
+		if got := expandEnv(test.input, "/home/user"); got != test.want {
+			t.Errorf("expandEnv(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	t.Setenv("TUNECLI_STREAMS", "https://radio.example")
+	original := `version: 1
+music_dirs:
+  - ~/Music
+  - $TUNECLI_STREAMS_DIR/archive
+  - path: library
+    recursive: false
+stations:
+  - name: Lo-Fi
+    url: ${TUNECLI_STREAMS}/lofi
+  - name: Jazz
+    url: https://jazz.example/live
+volume: 80
+`
+	path := writeConfig(t, original)
+	t.Setenv("TUNECLI_STREAMS_DIR", "/srv")
+
+	config, err := LoadConfigFrom(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := config.Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("config mode after save = %v, want 0600", info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, raw := range []string{"- ~/Music\n", "- $TUNECLI_STREAMS_DIR/archive\n", "path: library\n", "url: ${TUNECLI_STREAMS}/lofi\n"} {
+		if !strings.Contains(string(data), raw) {
+			t.Errorf("saved config does not contain %q:\n%s", raw, data)
+		}
+	}
+
+	reloaded, err := LoadConfigFrom(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !slices.Equal(reloaded.MusicDirs, config.MusicDirs) {
+		t.Errorf("reloaded music_dirs = %+v, want %+v", reloaded.MusicDirs, config.MusicDirs)
+	}
+	if !slices.Equal(reloaded.Stations, config.Stations) {
+		t.Errorf("reloaded stations = %+v, want %+v", reloaded.Stations, config.Stations)
+	}
+	if reloaded.Volume != config.Volume || reloaded.Version != config.Version {
+		t.Errorf("reloaded volume and version = %d, %d, want %d, %d", reloaded.Volume, reloaded.Version, config.Volume, config.Version)
+	}
+}
+
+func TestResolveDir(t *testing.T) {
+	t.Setenv("TUNECLI_MUSIC", "/srv/music")
+	t.Setenv("TUNECLI_RELATIVE", "shared")
+
+	home, baseDir := "/home/user", "/home/user/.config/tunecli"
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{"absolute", "/mnt/audio", "/mnt/audio"},
+		{"absolute with variable text", "/mnt/$TUNECLI_MUSIC", "/mnt/$TUNECLI_MUSIC"},
+		{"home", "~", "/home/user"},
+		{"under home", "~/Music", "/home/user/Music"},
+		{"home before variables", "~/$TUNECLI_MUSIC", "/home/user/$TUNECLI_MUSIC"},
+		{"variable", "$TUNECLI_MUSIC/rock", "/srv/music/rock"},
+		{"braced variable", "${TUNECLI_MUSIC}/jazz", "/srv/music/jazz"},
+		{"variable expanding to relative", "$TUNECLI_RELATIVE/music", "/home/user/.config/tunecli/shared/music"},
+		{"unset variable", "$TUNECLI_UNSET/music", "/home/user/.config/tunecli/$TUNECLI_UNSET/music"},
+		{"relative", "music", "/home/user/.config/tunecli/music"},
+		{"relative parent", "../music", "/home/user/.config/music"},
+		{"tilde user is relative", "~other/music", "/home/user/.config/tunecli/~other/music"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := resolveDir(test.dir, home, baseDir); got != test.want {
+				t.Errorf("resolveDir(%q) = %q, want %q", test.dir, got, test.want)
+			}
+		})
+	}
+}
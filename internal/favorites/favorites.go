@@ -0,0 +1,80 @@
+package favorites
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+type Store struct {
+	path  string
+	items map[string]bool
+}
+
+func LoadStore() (*Store, error) {
+	cfgDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user config directory: %s", err)
+	}
+
+	store := &Store{
+		path:  filepath.Join(cfgDir, "tunecli", "favorites.json"),
+		items: make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read favorites file: %s", err)
+	}
+
+	var items []string
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal favorites: %s", err)
+	}
+	for _, item := range items {
+		store.items[item] = true
+	}
+
+	return store, nil
+}
+
+func (store *Store) Contains(item string) bool {
+	return store.items[item]
+}
+
+func (store *Store) Toggle(item string) bool {
+	if store.items[item] {
+		delete(store.items, item)
+		return false
+	}
+
+	store.items[item] = true
+	return true
+}
+
+func (store *Store) Items() []string {
+	return slices.Sorted(maps.Keys(store.items))
+}
+
+func (store *Store) Save() error {
+	data, err := json.Marshal(store.Items())
+	if err != nil {
+		return fmt.Errorf("failed to marshal favorites: %s", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(store.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %s", err)
+	}
+
+	if err := os.WriteFile(store.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write favorites file: %s", err)
+	}
+
+	return nil
+}
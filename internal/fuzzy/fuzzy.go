@@ -0,0 +1,62 @@
+package fuzzy
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	matchScore       = 1
+	boundaryBonus    = 8
+	consecutiveBonus = 8
+	maxGapPenalty    = 3
+)
+
+// Match reports whether the runes of pattern appear in text in order. The
+// score rewards matches at word boundaries and runs of consecutive
+// characters, and positions holds the byte offset of each matched rune.
+func Match(pattern string, text string) (int, []int, bool) {
+	if pattern == "" {
+		return 0, nil, true
+	}
+
+	needle := []rune(pattern)
+	positions := make([]int, 0, len(needle))
+	score := 0
+	end := -1
+	previous := ' '
+
+	for offset, char := range text {
+		if len(positions) == len(needle) {
+			break
+		}
+		if char != needle[len(positions)] {
+			previous = char
+			continue
+		}
+
+		score += matchScore
+		switch {
+		case offset == end:
+			score += consecutiveBonus
+		case isBoundary(previous):
+			score += boundaryBonus
+		}
+		if end >= 0 && offset != end {
+			score -= min(utf8.RuneCountInString(text[end:offset]), maxGapPenalty)
+		}
+
+		positions = append(positions, offset)
+		end = offset + utf8.RuneLen(char)
+		previous = char
+	}
+
+	if len(positions) < len(needle) {
+		return 0, nil, false
+	}
+	return score, positions, true
+}
+
+func isBoundary(char rune) bool {
+	return strings.ContainsRune(" _-./()[]", char)
+}
@@ -0,0 +1,245 @@
+package headless
+
+import (
+	"log"
+	"os"
+
+	"github.com/sokolawesome/tunecli/internal/mpris"
+	"github.com/sokolawesome/tunecli/internal/notify"
+	"github.com/sokolawesome/tunecli/internal/player"
+)
+
+type Daemon struct {
+	player      *player.Player
+	mprisServer *mpris.MprisServer
+	notifier    *notify.Notifier
+	cmdChan     <-chan string
+	status      string
+	title       string
+	volume      float64
+	position    float64
+	duration    float64
+	loop        player.LoopMode
+	shuffle     bool
+}
+
+func NewDaemon(player *player.Player, mprisServer *mpris.MprisServer, notifier *notify.Notifier, cmdChan <-chan string) *Daemon {
+	return &Daemon{
+		player:      player,
+		mprisServer: mprisServer,
+		notifier:    notifier,
+		cmdChan:     cmdChan,
+		status:      "Stopped",
+	}
+}
+
+func (daemon *Daemon) Run(tracks []string, signals <-chan os.Signal) {
+	daemon.playTracks(tracks)
+
+	for {
+		select {
+		case command := <-daemon.cmdChan:
+			daemon.handleCommand(command)
+
+		case event := <-daemon.player.Events:
+			daemon.handleEvent(event)
+
+		case state := <-daemon.player.StateChanges:
+			if state.Title != daemon.title {
+				daemon.setTitle(state.Title)
+				daemon.notifier.TrackChanged(state.Title, "", nowPlaying(state))
+			}
+			daemon.syncVolume(state)
+			daemon.position, daemon.duration = state.Position, state.Duration
+			if err := daemon.mprisServer.UpdatePosition(state.Position); err != nil {
+				log.Printf("Failed to update MPRIS position: %v", err)
+			}
+
+		case sig := <-signals:
+			log.Printf("Received %s, shutting down", sig)
+			return
+		}
+	}
+}
+
+func (daemon *Daemon) playTracks(tracks []string) {
+	if len(tracks) == 0 {
+		return
+	}
+
+	if err := daemon.player.LoadFile(tracks[0]); err != nil {
+		log.Printf("Failed to load file: %v", err)
+		return
+	}
+
+	for _, track := range tracks[1:] {
+		if err := daemon.player.AppendFile(track); err != nil {
+			log.Printf("Failed to enqueue file: %v", err)
+		}
+	}
+
+	daemon.setStatus("Playing")
+}
+
+func (daemon *Daemon) handleCommand(command string) {
+	if location, ok := mpris.ParseOpenCommand(command); ok {
+		daemon.playTracks([]string{location})
+		return
+	}
+
+	if volume, ok := mpris.ParseVolumeCommand(command); ok {
+		if err := daemon.player.SetVolume(volume); err != nil {
+			log.Printf("Failed to set volume: %v", err)
+		}
+		return
+	}
+
+	if seconds, absolute, ok := mpris.ParseSeekCommand(command); ok {
+		daemon.seek(seconds, absolute)
+		return
+	}
+
+	if mode, ok := mpris.ParseLoopCommand(command); ok {
+		daemon.setLoop(mode)
+		return
+	}
+
+	if shuffle, ok := mpris.ParseShuffleCommand(command); ok {
+		daemon.setShuffle(shuffle)
+		return
+	}
+
+	if command == "stop" && daemon.status != "Stopped" {
+		if err := daemon.player.Stop(); err != nil {
+			log.Printf("Failed to stop: %v", err)
+			return
+		}
+		daemon.setStatus("Stopped")
+		daemon.setTitle("")
+		return
+	}
+
+	switch {
+	case command == "toggle_pause" && daemon.status != "Stopped":
+	case command == "play" && daemon.status == "Paused":
+	case command == "pause" && daemon.status == "Playing":
+	default:
+		return
+	}
+
+	if err := daemon.player.TogglePause(); err != nil {
+		log.Printf("Failed to toggle pause: %v", err)
+		return
+	}
+
+	if daemon.status == "Playing" {
+		daemon.setStatus("Paused")
+	} else {
+		daemon.setStatus("Playing")
+	}
+}
+
+func (daemon *Daemon) handleEvent(event player.Event) {
+	switch {
+	case event.Type == player.EventFileLoaded && daemon.status == "Stopped":
+		daemon.setStatus("Playing")
+	case event.Type == player.EventPaused && daemon.status != "Paused":
+		daemon.setStatus("Paused")
+	case event.Type == player.EventResumed && daemon.status == "Paused":
+		daemon.setStatus("Playing")
+	case event.Type == player.EventStopped && daemon.status != "Stopped":
+		daemon.setStatus("Stopped")
+	}
+}
+
+func (daemon *Daemon) seek(seconds float64, absolute bool) {
+	if daemon.status == "Stopped" {
+		return
+	}
+
+	var err error
+	target := seconds
+	if absolute {
+		if daemon.duration > 0 && seconds > daemon.duration {
+			return
+		}
+		err = daemon.player.SeekAbsolute(seconds)
+	} else {
+		target = daemon.position + seconds
+		err = daemon.player.SeekRelative(seconds)
+	}
+	if err != nil {
+		log.Printf("Failed to seek: %v", err)
+		return
+	}
+
+	if err := daemon.mprisServer.Seeked(target); err != nil {
+		log.Printf("Failed to update MPRIS position: %v", err)
+	}
+}
+
+func (daemon *Daemon) setLoop(mode player.LoopMode) {
+	if err := daemon.player.SetLoop(mode); err != nil {
+		log.Printf("Failed to set loop mode: %v", err)
+	} else {
+		daemon.loop = mode
+	}
+
+	if err := daemon.mprisServer.SetLoopStatus(mpris.LoopStatusNames[daemon.loop]); err != nil {
+		log.Printf("Failed to update MPRIS loop status: %v", err)
+	}
+}
+
+func (daemon *Daemon) setShuffle(enabled bool) {
+	if enabled != daemon.shuffle {
+		if err := daemon.player.SetShuffle(enabled); err != nil {
+			log.Printf("Failed to set shuffle: %v", err)
+		} else {
+			daemon.shuffle = enabled
+		}
+	}
+
+	if err := daemon.mprisServer.SetShuffle(daemon.shuffle); err != nil {
+		log.Printf("Failed to update MPRIS shuffle: %v", err)
+	}
+}
+
+func nowPlaying(state player.State) string {
+	for _, entry := range state.Playlist {
+		if entry.Current {
+			return entry.Filename
+		}
+	}
+	return ""
+}
+
+func (daemon *Daemon) setStatus(status string) {
+	daemon.status = status
+
+	if err := daemon.mprisServer.SetPlaybackStatus(status); err != nil {
+		log.Printf("Failed to update MPRIS status: %v", err)
+	}
+}
+
+func (daemon *Daemon) setTitle(title string) {
+	daemon.title = title
+
+	if err := daemon.mprisServer.SetMetadata(title); err != nil {
+		log.Printf("Failed to update MPRIS metadata: %v", err)
+	}
+}
+
+func (daemon *Daemon) syncVolume(state player.State) {
+	volume := state.Volume / 100
+	if state.Muted {
+		volume = 0
+	}
+	if volume == daemon.volume {
+		return
+	}
+	daemon.volume = volume
+
+	if err := daemon.mprisServer.SetVolume(volume); err != nil {
+		log.Printf("Failed to update MPRIS volume: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const DefaultSize = 100
+
+type Entry struct {
+	Path     string    `json:"path"`
+	Title    string    `json:"title"`
+	PlayedAt time.Time `json:"played_at"`
+}
+
+type History struct {
+	path    string
+	size    int
+	entries []Entry
+}
+
+func Load(size int, persist bool) (*History, error) {
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	history := &History{size: size}
+	if !persist {
+		return history, nil
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return history, fmt.Errorf("failed to get user cache directory: %s", err)
+	}
+	history.path = filepath.Join(cacheDir, "tunecli", "history.json")
+
+	data, err := os.ReadFile(history.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return history, nil
+		}
+		return history, fmt.Errorf("failed to read history file: %s", err)
+	}
+
+	if err := json.Unmarshal(data, &history.entries); err != nil {
+		return history, fmt.Errorf("failed to unmarshal history: %s", err)
+	}
+	history.trim()
+
+	return history, nil
+}
+
+func (history *History) Add(entry Entry) {
+	history.entries = append(history.entries, entry)
+	history.trim()
+}
+
+func (history *History) trim() {
+	if len(history.entries) > history.size {
+		history.entries = slices.Clone(history.entries[len(history.entries)-history.size:])
+	}
+}
+
+func (history *History) Recent() []Entry {
+	recent := slices.Clone(history.entries)
+	slices.Reverse(recent)
+	return recent
+}
+
+func (history *History) Len() int {
+	return len(history.entries)
+}
+
+func (history *History) Save() error {
+	if history.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(history.entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %s", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(history.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %s", err)
+	}
+
+	if err := os.WriteFile(history.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write history file: %s", err)
+	}
+
+	return nil
+}
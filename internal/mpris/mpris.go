@@ -3,49 +3,147 @@ package mpris
 import (
 	"fmt"
 	"log"
+	"maps"
+	"math"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/godbus/dbus/v5"
 	"github.com/godbus/dbus/v5/prop"
+	"github.com/sokolawesome/tunecli/internal/player"
+	"github.com/sokolawesome/tunecli/internal/scanner"
 )
 
 const (
+	rootInterface = "org.mpris.MediaPlayer2"
 	interfaceName = "org.mpris.MediaPlayer2.Player"
 	busName       = "org.mpris.MediaPlayer2.tunecli"
 	objectPath    = "/org/mpris/MediaPlayer2"
+	trackPath     = "/org/tunecli/track/"
+
+	volumeCommandPrefix   = "set_volume:"
+	openCommandPrefix     = "open:"
+	seekCommandPrefix     = "seek:"
+	positionCommandPrefix = "set_position:"
+	loopCommandPrefix     = "set_loop:"
+	shuffleCommandPrefix  = "set_shuffle:"
 )
 
+var LoopStatusNames = map[player.LoopMode]string{
+	player.LoopNone:     "None",
+	player.LoopTrack:    "Track",
+	player.LoopPlaylist: "Playlist",
+}
+
+func requestBusName(conn *dbus.Conn) error {
+	for _, name := range []string{busName, fmt.Sprintf("%s.instance%d", busName, os.Getpid())} {
+		reply, err := conn.RequestName(name, dbus.NameFlagDoNotQueue)
+		if err != nil {
+			return fmt.Errorf("failed to request bus name: %s", err)
+		}
+		if reply == dbus.RequestNameReplyPrimaryOwner {
+			return nil
+		}
+		log.Printf("MPRIS bus name %s is taken", name)
+	}
+
+	return fmt.Errorf("failed to become primary owner of bus name")
+}
+
+type rootServer struct{}
+
+func (rootServer) Raise() *dbus.Error {
+	return nil
+}
+
+func (rootServer) Quit() *dbus.Error {
+	return nil
+}
+
 type MprisServer struct {
-	conn    *dbus.Conn
-	CmdChan chan<- string
-	props   *prop.Properties
+	conn       *dbus.Conn
+	CmdChan    chan<- string
+	props      *prop.Properties
+	trackMutex sync.Mutex
+	trackCount uint64
+	trackID    dbus.ObjectPath
 }
 
-func NewMprisServer(cmdChan chan<- string) (*MprisServer, error) {
+func NewMprisServer(cmdChan chan<- string, version string) (*MprisServer, error) {
 	conn, err := dbus.SessionBus()
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to dbus: %s", err)
 	}
-	reply, err := conn.RequestName(busName, dbus.NameFlagDoNotQueue)
-	if err != nil {
-		return nil, fmt.Errorf("failed to request bus name: %s", err)
-	}
-	if reply != dbus.RequestNameReplyPrimaryOwner {
-		return nil, fmt.Errorf("failed to become primary owner of bus name")
+	if err := requestBusName(conn); err != nil {
+		return nil, err
 	}
 
 	server := &MprisServer{conn: conn, CmdChan: cmdChan}
 
-	if err := conn.Export(server, objectPath, interfaceName); err != nil {
+	if err := conn.ExportWithMap(server, map[string]string{"SeekOffset": "Seek"}, objectPath, interfaceName); err != nil {
 		return nil, fmt.Errorf("failed to export player server: %s", err)
 	}
 
+	if err := conn.Export(rootServer{}, objectPath, rootInterface); err != nil {
+		return nil, fmt.Errorf("failed to export root server: %s", err)
+	}
+
 	propsSpec := prop.Map{
+		rootInterface: {
+			"CanQuit":             {Value: false, Writable: false, Emit: prop.EmitConst},
+			"CanRaise":            {Value: false, Writable: false, Emit: prop.EmitConst},
+			"HasTrackList":        {Value: false, Writable: false, Emit: prop.EmitConst},
+			"Identity":            {Value: "TuneCLI " + version, Writable: false, Emit: prop.EmitConst},
+			"SupportedUriSchemes": {Value: []string{"file", "http", "https"}, Writable: false, Emit: prop.EmitConst},
+			"SupportedMimeTypes":  {Value: scanner.MimeTypes(), Writable: false, Emit: prop.EmitConst},
+		},
 		interfaceName: {
 			"PlaybackStatus": {
 				Value:    "Stopped",
 				Writable: false,
 				Emit:     prop.EmitTrue,
 			},
+			"Metadata": {
+				Value:    map[string]dbus.Variant{},
+				Writable: false,
+				Emit:     prop.EmitTrue,
+			},
+			"Volume": {
+				Value:    1.0,
+				Writable: true,
+				Emit:     prop.EmitTrue,
+				Callback: server.handleVolumeChange,
+			},
+			"LoopStatus": {
+				Value:    "None",
+				Writable: true,
+				Emit:     prop.EmitTrue,
+				Callback: server.handleLoopStatusChange,
+			},
+			"Shuffle": {
+				Value:    false,
+				Writable: true,
+				Emit:     prop.EmitTrue,
+				Callback: server.handleShuffleChange,
+			},
+			"Position": {
+				Value:    int64(0),
+				Writable: false,
+				Emit:     prop.EmitFalse,
+			},
+			"Rate":          {Value: 1.0, Writable: false, Emit: prop.EmitConst},
+			"MinimumRate":   {Value: 1.0, Writable: false, Emit: prop.EmitConst},
+			"MaximumRate":   {Value: 1.0, Writable: false, Emit: prop.EmitConst},
+			"CanGoNext":     {Value: false, Writable: false, Emit: prop.EmitConst},
+			"CanGoPrevious": {Value: false, Writable: false, Emit: prop.EmitConst},
+			"CanPlay":       {Value: true, Writable: false, Emit: prop.EmitConst},
+			"CanPause":      {Value: true, Writable: false, Emit: prop.EmitConst},
+			"CanSeek":       {Value: true, Writable: false, Emit: prop.EmitConst},
+			"CanControl":    {Value: true, Writable: false, Emit: prop.EmitConst},
 		},
 	}
 
@@ -66,11 +164,218 @@ func (server *MprisServer) SetPlaybackStatus(status string) error {
 	return nil
 }
 
+func (server *MprisServer) SetMetadata(title string) error {
+	server.trackMutex.Lock()
+	server.trackID = ""
+	metadata := map[string]dbus.Variant{}
+	if title != "" {
+		server.trackCount++
+		server.trackID = dbus.ObjectPath(fmt.Sprintf("%s%d", trackPath, server.trackCount))
+		metadata["mpris:trackid"] = dbus.MakeVariant(server.trackID)
+		metadata["xesam:title"] = dbus.MakeVariant(title)
+	}
+	server.trackMutex.Unlock()
+
+	if err := server.props.Set(interfaceName, "Metadata", dbus.MakeVariant(metadata)); err != nil {
+		return fmt.Errorf("failed to set metadata: %s", err)
+	}
+	return nil
+}
+
+func (server *MprisServer) SetVolume(volume float64) error {
+	if err := server.props.Set(interfaceName, "Volume", dbus.MakeVariant(volume)); err != nil {
+		return fmt.Errorf("failed to set volume: %s", err)
+	}
+	return nil
+}
+
+func (server *MprisServer) SetLoopStatus(status string) error {
+	if err := server.props.Set(interfaceName, "LoopStatus", dbus.MakeVariant(status)); err != nil {
+		return fmt.Errorf("failed to set loop status: %s", err)
+	}
+	return nil
+}
+
+func (server *MprisServer) SetShuffle(shuffle bool) error {
+	if err := server.props.Set(interfaceName, "Shuffle", dbus.MakeVariant(shuffle)); err != nil {
+		return fmt.Errorf("failed to set shuffle: %s", err)
+	}
+	return nil
+}
+
+func (server *MprisServer) UpdatePosition(seconds float64) error {
+	position := int64(max(seconds, 0) * 1e6)
+	if err := server.props.Set(interfaceName, "Position", dbus.MakeVariant(position)); err != nil {
+		return fmt.Errorf("failed to set position: %s", err)
+	}
+	return nil
+}
+
+func (server *MprisServer) Seeked(seconds float64) error {
+	if err := server.UpdatePosition(seconds); err != nil {
+		return err
+	}
+	if err := server.conn.Emit(objectPath, interfaceName+".Seeked", int64(max(seconds, 0)*1e6)); err != nil {
+		return fmt.Errorf("failed to emit seeked signal: %s", err)
+	}
+	return nil
+}
+
+func (server *MprisServer) handleVolumeChange(change *prop.Change) *dbus.Error {
+	volume, ok := change.Value.(float64)
+	if !ok || math.IsNaN(volume) {
+		return prop.ErrInvalidArg
+	}
+
+	server.CmdChan <- fmt.Sprintf("%s%g", volumeCommandPrefix, min(max(volume, 0), 1)*100)
+	return nil
+}
+
+func (server *MprisServer) handleLoopStatusChange(change *prop.Change) *dbus.Error {
+	status, ok := change.Value.(string)
+	if !ok || !slices.Contains(slices.Collect(maps.Values(LoopStatusNames)), status) {
+		return prop.ErrInvalidArg
+	}
+
+	server.CmdChan <- loopCommandPrefix + status
+	return nil
+}
+
+func (server *MprisServer) handleShuffleChange(change *prop.Change) *dbus.Error {
+	shuffle, ok := change.Value.(bool)
+	if !ok {
+		return prop.ErrInvalidArg
+	}
+
+	server.CmdChan <- shuffleCommandPrefix + strconv.FormatBool(shuffle)
+	return nil
+}
+
+func ParseOpenCommand(command string) (string, bool) {
+	uri, ok := strings.CutPrefix(command, openCommandPrefix)
+	if !ok {
+		return "", false
+	}
+
+	parsed, err := url.Parse(uri)
+	if err == nil && parsed.Scheme == "file" {
+		return parsed.Path, true
+	}
+	return uri, true
+}
+
+func ParseSeekCommand(command string) (float64, bool, bool) {
+	value, absolute := strings.CutPrefix(command, positionCommandPrefix)
+	if !absolute {
+		var ok bool
+		if value, ok = strings.CutPrefix(command, seekCommandPrefix); !ok {
+			return 0, false, false
+		}
+	}
+
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false, false
+	}
+	return seconds, absolute, true
+}
+
+func ParseVolumeCommand(command string) (float64, bool) {
+	value, ok := strings.CutPrefix(command, volumeCommandPrefix)
+	if !ok {
+		return 0, false
+	}
+
+	volume, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return volume, true
+}
+
+func ParseLoopCommand(command string) (player.LoopMode, bool) {
+	status, ok := strings.CutPrefix(command, loopCommandPrefix)
+	if !ok {
+		return 0, false
+	}
+
+	for mode, name := range LoopStatusNames {
+		if name == status {
+			return mode, true
+		}
+	}
+	return 0, false
+}
+
+func ParseShuffleCommand(command string) (bool, bool) {
+	value, ok := strings.CutPrefix(command, shuffleCommandPrefix)
+	if !ok {
+		return false, false
+	}
+
+	shuffle, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, false
+	}
+	return shuffle, true
+}
+
 func (server *MprisServer) PlayPause() *dbus.Error {
 	server.CmdChan <- "toggle_pause"
 	return nil
 }
 
+func (server *MprisServer) Play() *dbus.Error {
+	server.CmdChan <- "play"
+	return nil
+}
+
+func (server *MprisServer) Pause() *dbus.Error {
+	server.CmdChan <- "pause"
+	return nil
+}
+
+func (server *MprisServer) OpenUri(uri string) *dbus.Error {
+	parsed, err := url.Parse(uri)
+	if err != nil || (parsed.Scheme != "file" && parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return dbus.MakeFailedError(fmt.Errorf("unsupported uri: %s", uri))
+	}
+
+	server.CmdChan <- openCommandPrefix + uri
+	return nil
+}
+
+func (server *MprisServer) Next() *dbus.Error {
+	return nil
+}
+
+func (server *MprisServer) Previous() *dbus.Error {
+	return nil
+}
+
+func (server *MprisServer) SeekOffset(offset int64) *dbus.Error {
+	server.CmdChan <- fmt.Sprintf("%s%g", seekCommandPrefix, float64(offset)/1e6)
+	return nil
+}
+
+func (server *MprisServer) SetPosition(trackID dbus.ObjectPath, position int64) *dbus.Error {
+	server.trackMutex.Lock()
+	current := server.trackID
+	server.trackMutex.Unlock()
+
+	if position < 0 || trackID != current {
+		return nil
+	}
+
+	server.CmdChan <- fmt.Sprintf("%s%g", positionCommandPrefix, float64(position)/1e6)
+	return nil
+}
+
+func (server *MprisServer) Stop() *dbus.Error {
+	server.CmdChan <- "stop"
+	return nil
+}
+
 func (server *MprisServer) Close() {
 	if err := server.conn.Close(); err != nil {
 		log.Printf("failed to close connection: %s", err)
@@ -0,0 +1,63 @@
+package mpris
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5/prop"
+	"github.com/sokolawesome/tunecli/internal/player"
+)
+
+func TestLoopStatusChange(t *testing.T) {
+	commands := make(chan string, 1)
+	server := &MprisServer{CmdChan: commands}
+
+	for mode, status := range LoopStatusNames {
+		if err := server.handleLoopStatusChange(&prop.Change{Value: status}); err != nil {
+			t.Fatalf("write %s: %v", status, err)
+		}
+		if got, ok := ParseLoopCommand(<-commands); !ok || got != mode {
+			t.Errorf("write %s parsed to %d, %t, want %d", status, got, ok, mode)
+		}
+	}
+
+	for _, value := range []any{"Shuffle", "track", 1} {
+		if err := server.handleLoopStatusChange(&prop.Change{Value: value}); err == nil {
+			t.Errorf("write %v was accepted", value)
+		}
+	}
+	if len(commands) != 0 {
+		t.Errorf("invalid writes sent %q", <-commands)
+	}
+}
+
+func TestShuffleChange(t *testing.T) {
+	commands := make(chan string, 1)
+	server := &MprisServer{CmdChan: commands}
+
+	for _, shuffle := range []bool{true, false} {
+		if err := server.handleShuffleChange(&prop.Change{Value: shuffle}); err != nil {
+			t.Fatalf("write %t: %v", shuffle, err)
+		}
+		if got, ok := ParseShuffleCommand(<-commands); !ok || got != shuffle {
+			t.Errorf("write %t parsed to %t, %t", shuffle, got, ok)
+		}
+	}
+
+	if err := server.handleShuffleChange(&prop.Change{Value: "true"}); err == nil {
+		t.Error("write of a string was accepted")
+	}
+}
+
+func TestParseCommandsRejectOthers(t *testing.T) {
+	for _, command := range []string{"toggle_pause", "set_volume:50", "set_loop:Always", "set_shuffle:maybe"} {
+		if _, ok := ParseLoopCommand(command); ok {
+			t.Errorf("ParseLoopCommand(%q) succeeded", command)
+		}
+		if _, ok := ParseShuffleCommand(command); ok {
+			t.Errorf("ParseShuffleCommand(%q) succeeded", command)
+		}
+	}
+	if mode, ok := ParseLoopCommand("set_loop:None"); !ok || mode != player.LoopNone {
+		t.Errorf("ParseLoopCommand(set_loop:None) = %d, %t", mode, ok)
+	}
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	notificationsName   = "org.freedesktop.Notifications"
+	notificationsPath   = "/org/freedesktop/Notifications"
+	notifyMethod        = notificationsName + ".Notify"
+	coalesceDelay       = time.Second
+	notificationTimeout = 5000
+)
+
+var coverNames = []string{"cover.jpg", "cover.png", "folder.jpg", "folder.png", "front.jpg", "front.png"}
+
+type Notifier struct {
+	conn    *dbus.Conn
+	mutex   sync.Mutex
+	timer   *time.Timer
+	id      uint32
+	summary string
+	body    string
+	image   string
+}
+
+func NewNotifier() (*Notifier, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to dbus: %s", err)
+	}
+
+	return &Notifier{conn: conn}, nil
+}
+
+func (notifier *Notifier) TrackChanged(title string, artist string, location string) {
+	if notifier == nil || title == "" {
+		return
+	}
+
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	notifier.summary, notifier.body, notifier.image = title, artist, coverImage(location)
+
+	if notifier.timer != nil {
+		notifier.timer.Stop()
+	}
+	notifier.timer = time.AfterFunc(coalesceDelay, notifier.send)
+}
+
+func (notifier *Notifier) send() {
+	notifier.mutex.Lock()
+	summary, body, image, replaces := notifier.summary, notifier.body, notifier.image, notifier.id
+	notifier.mutex.Unlock()
+
+	hints := map[string]dbus.Variant{}
+	if image != "" {
+		hints["image-path"] = dbus.MakeVariant(image)
+	}
+
+	var id uint32
+	object := notifier.conn.Object(notificationsName, notificationsPath)
+	call := object.Call(notifyMethod, 0, "tunecli", replaces, image, summary, body, []string{}, hints, int32(notificationTimeout))
+	if err := call.Store(&id); err != nil {
+		log.Printf("Failed to send notification: %v", err)
+		return
+	}
+
+	notifier.mutex.Lock()
+	notifier.id = id
+	notifier.mutex.Unlock()
+}
+
+func coverImage(location string) string {
+	if location == "" || strings.Contains(location, "://") {
+		return ""
+	}
+
+	dir := filepath.Dir(location)
+	for _, name := range coverNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			if absolute, err := filepath.Abs(path); err == nil {
+				return absolute
+			}
+		}
+	}
+
+	return ""
+}
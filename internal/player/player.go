@@ -1,42 +1,548 @@
 package player
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
 type Player struct {
-	Conn net.Conn
-	cmd  *exec.Cmd
+	Conn         net.Conn
+	SocketPath   string
+	StateChanges chan State
+	Events       chan Event
+	cmd          *exec.Cmd
+	outputDone   chan struct{}
+	state        State
+	requestID    int
+	pending      map[int]chan mpvEvent
+	pendingMutex sync.Mutex
+	startAt      float64
+	writeMutex   sync.Mutex
+	crossfade    float64
+	fadeOutSet   bool
+	fade         time.Duration
+	fadeInVolume float64
+	fadeMutex    sync.Mutex
+	fading       *pauseFade
+	fadeVolume   float64
+	mediaTitle   string
+	streamTitle  string
+	paused       bool
 }
 
-func NewPlayer() (*Player, error) {
-	cmd := exec.Command("mpv",
+type State struct {
+	Position float64
+	Duration float64
+	Playlist []PlaylistEntry
+	Volume   float64
+	Muted    bool
+	Idle     bool
+	Title    string
+}
+
+type PlaylistEntry struct {
+	Filename string `json:"filename"`
+	Title    string `json:"title"`
+	Current  bool   `json:"current"`
+}
+
+func (entry PlaylistEntry) Name() string {
+	if entry.Title != "" {
+		return entry.Title
+	}
+	return filepath.Base(entry.Filename)
+}
+
+type AudioDevice struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type pauseFade struct {
+	pausing bool
+	cancel  chan struct{}
+	done    chan struct{}
+}
+
+type Options struct {
+	ReplayGain  string
+	AudioDevice string
+	Equalizer   []float64
+	Gapless     bool
+	Crossfade   float64
+	Fade        time.Duration
+	AudioFilter string
+	Verbose     bool
+	Ytdl        bool
+	ExtraArgs   []string
+}
+
+const (
+	ReplayGainOff   = "off"
+	ReplayGainTrack = "track"
+	ReplayGainAlbum = "album"
+)
+
+type Status uint8
+
+const (
+	StatusStopped Status = iota
+	StatusPlaying
+	StatusPaused
+)
+
+type EventType uint8
+
+const (
+	EventFileLoaded EventType = iota
+	EventPaused
+	EventResumed
+	EventStopped
+	EventError
+	EventTrackEnded
+)
+
+type Event struct {
+	Type    EventType
+	Message string
+}
+
+type LoopMode uint8
+
+const (
+	LoopNone LoopMode = iota
+	LoopTrack
+	LoopPlaylist
+)
+
+type mpvEvent struct {
+	Event     string          `json:"event"`
+	Name      string          `json:"name"`
+	Data      json.RawMessage `json:"data"`
+	Reason    string          `json:"reason"`
+	FileError string          `json:"file_error"`
+	RequestID int             `json:"request_id"`
+	Error     string          `json:"error"`
+}
+
+var equalizerFrequencies = []int{31, 62, 125, 250, 500, 1000, 2000, 4000, 8000, 16000}
+
+const socketPattern = "mpv-*.sock"
+
+const equalizerLabel = "@eq"
+const crossfadeLabel = "@crossfade"
+const audioFilterLabel = "@filter"
+
+const requestTimeout = 2 * time.Second
+const writeTimeout = 2 * time.Second
+const quitTimeout = 2 * time.Second
+const fadeSteps = 10
+const maxMessageSize = 4 * 1024 * 1024
+const eventBufferSize = 16
+
+var observedProperties = []string{
+	"time-pos",
+	"duration",
+	"playlist",
+	"volume",
+	"mute",
+	"idle-active",
+	"pause",
+	"media-title",
+	"metadata/by-key/icy-title",
+}
+
+func logOutput(output io.Reader, done chan<- struct{}) {
+	defer close(done)
+
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		log.Printf("[mpv] %s", scanner.Text())
+	}
+}
+
+// SocketDir holds one mpv IPC socket per running instance, so several
+// instances never share a socket.
+func SocketDir() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "tunecli")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("tunecli-%d", os.Getuid()))
+}
+
+func SocketPaths() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(SocketDir(), socketPattern))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mpv sockets: %s", err)
+	}
+	return paths, nil
+}
+
+func NewPlayer(options Options) (*Player, error) {
+	if err := os.MkdirAll(SocketDir(), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %s", err)
+	}
+	socketPath := filepath.Join(SocketDir(), fmt.Sprintf("mpv-%d.sock", os.Getpid()))
+
+	// Extra arguments go first so the options tunecli depends on win.
+	args := append(slices.Clone(options.ExtraArgs),
 		"--idle=yes",
 		"--no-video",
-		"--no-terminal",
-		"--input-ipc-server=/tmp/tunecli-mpv.sock",
+		"--gapless-audio="+yesNo(options.Gapless),
+		"--ytdl="+yesNo(options.Ytdl),
+		"--input-ipc-server="+socketPath,
 	)
 
+	if options.Verbose {
+		args = append(args, "--input-terminal=no", "--msg-color=no")
+	} else {
+		args = append(args, "--no-terminal")
+	}
+
+	if mode := ReplayGainMode(options.ReplayGain); mode != ReplayGainOff {
+		args = append(args, "--replaygain="+mode)
+	}
+
+	if options.AudioDevice != "" {
+		args = append(args, "--audio-device="+options.AudioDevice)
+	}
+
+	if filter := equalizerFilter(options.Equalizer); filter != "" {
+		args = append(args, "--af="+filter)
+	}
+
+	if options.AudioFilter != "" {
+		if err := ValidateAudioFilter(options.AudioFilter); err != nil {
+			log.Printf("Ignoring audio filter: %v", err)
+		} else {
+			args = append(args, "--af-add="+audioFilterLabel+":"+options.AudioFilter)
+		}
+	}
+
+	if options.Ytdl && !hasYtdl() {
+		log.Print("yt-dlp not found, so YouTube and similar page URLs will not play; install yt-dlp or set ytdl: false")
+	}
+
+	cmd := exec.Command("mpv", args...)
+
+	var stderr io.ReadCloser
+	if options.Verbose {
+		var err error
+		stderr, err = cmd.StderrPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture mpv output: %s", err)
+		}
+	}
+
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start mpv: %s", err)
 	}
 
+	var outputDone chan struct{}
+	if stderr != nil {
+		outputDone = make(chan struct{})
+		go logOutput(stderr, outputDone)
+	}
+
 	time.Sleep(200 * time.Millisecond)
 
-	conn, err := net.Dial("unix", "/tmp/tunecli-mpv.sock")
+	conn, err := net.Dial("unix", socketPath)
 	if err != nil {
+		abortStart(cmd, outputDone, nil, socketPath)
 		return nil, fmt.Errorf("failed to connect to mpv: %s", err)
 	}
 
-	return &Player{
-		Conn: conn,
-		cmd:  cmd,
-	}, nil
+	player := &Player{
+		Conn:         conn,
+		SocketPath:   socketPath,
+		outputDone:   outputDone,
+		StateChanges: make(chan State, 1),
+		Events:       make(chan Event, eventBufferSize),
+		cmd:          cmd,
+		pending:      make(map[int]chan mpvEvent),
+		crossfade:    options.Crossfade,
+		fade:         options.Fade,
+	}
+
+	for i, property := range observedProperties {
+		command := map[string]any{"command": []any{"observe_property", i + 1, property}}
+		if err := player.sendCommand(command); err != nil {
+			abortStart(cmd, outputDone, conn, socketPath)
+			return nil, fmt.Errorf("failed to observe %s: %s", property, err)
+		}
+	}
+
+	go player.readEvents()
+
+	return player, nil
+}
+
+// abortStart tears down an mpv that started but could not be set up, so a
+// failed NewPlayer leaves no process or socket behind.
+func abortStart(cmd *exec.Cmd, outputDone <-chan struct{}, conn net.Conn, socketPath string) {
+	if conn != nil {
+		if err := conn.Close(); err != nil {
+			log.Printf("failed to close connection: %s", err)
+		}
+	}
+
+	if err := cmd.Process.Kill(); err != nil {
+		log.Printf("failed to kill mpv process: %s", err)
+	}
+	if outputDone != nil {
+		<-outputDone
+	}
+	cmd.Wait()
+
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to remove mpv socket: %s", err)
+	}
+}
+
+func hasYtdl() bool {
+	for _, name := range []string{"yt-dlp", "youtube-dl"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func yesNo(enabled bool) string {
+	if enabled {
+		return "yes"
+	}
+	return "no"
+}
+
+func (player *Player) readEvents() {
+	scanner := bufio.NewScanner(player.Conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+
+	for scanner.Scan() {
+		var event mpvEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+
+		if event.Event == "" && event.RequestID != 0 {
+			player.handleResponse(event)
+			continue
+		}
+
+		switch event.Event {
+		case "property-change":
+			player.handlePropertyChange(event)
+		case "end-file":
+			player.handleEndFile(event)
+		case "file-loaded":
+			player.handleFileLoaded()
+		}
+	}
+}
+
+func (player *Player) handleResponse(response mpvEvent) {
+	player.pendingMutex.Lock()
+	responseChan, ok := player.pending[response.RequestID]
+	delete(player.pending, response.RequestID)
+	player.pendingMutex.Unlock()
+
+	if ok {
+		responseChan <- response
+	}
+}
+
+func (player *Player) handlePropertyChange(event mpvEvent) {
+	switch event.Name {
+	case "time-pos":
+		player.state.Position = parseFloat(event.Data)
+	case "duration":
+		player.state.Duration = parseFloat(event.Data)
+		player.applyFadeOut()
+	case "playlist":
+		var playlist []PlaylistEntry
+		if err := json.Unmarshal(event.Data, &playlist); err != nil {
+			log.Printf("failed to parse playlist: %s", err)
+		}
+		player.state.Playlist = playlist
+	case "volume":
+		player.state.Volume = parseFloat(event.Data)
+	case "mute":
+		player.state.Muted = parseBool(event.Data)
+	case "idle-active":
+		idle := parseBool(event.Data)
+		if idle && !player.state.Idle {
+			player.emit(Event{Type: EventStopped})
+		}
+		player.state.Idle = idle
+	case "pause":
+		player.setPaused(parseBool(event.Data))
+		return
+	case "media-title":
+		player.mediaTitle = parseString(event.Data)
+		player.updateTitle()
+	case "metadata/by-key/icy-title":
+		player.streamTitle = parseString(event.Data)
+		player.updateTitle()
+	default:
+		return
+	}
+
+	player.publishState()
+}
+
+func (player *Player) setPaused(paused bool) {
+	if paused == player.paused {
+		return
+	}
+
+	player.paused = paused
+	if paused {
+		player.emit(Event{Type: EventPaused})
+	} else {
+		player.emit(Event{Type: EventResumed})
+	}
+}
+
+func (player *Player) updateTitle() {
+	title := player.streamTitle
+	if title == "" {
+		title = player.mediaTitle
+	}
+
+	if title != "" && title != player.state.Title {
+		log.Printf("Now playing: %s", title)
+	}
+	player.state.Title = title
+}
+
+func (player *Player) handleFileLoaded() {
+	player.emit(Event{Type: EventFileLoaded})
+	player.applyFadeIn()
+
+	player.pendingMutex.Lock()
+	fadeInVolume := player.fadeInVolume
+	player.fadeInVolume = 0
+	player.pendingMutex.Unlock()
+
+	if fadeInVolume > 0 {
+		go player.rampVolume(0, fadeInVolume, nil)
+	}
+
+	player.pendingMutex.Lock()
+	startAt := player.startAt
+	player.startAt = 0
+	player.pendingMutex.Unlock()
+
+	if startAt > 0 {
+		if err := player.SeekAbsolute(startAt); err != nil {
+			log.Printf("failed to seek to start position: %s", err)
+		}
+	}
+}
+
+// mpv plays a single file at a time, so tracks cannot overlap. Crossfade is
+// approximated by fading each track in and fading it out over its last seconds.
+func (player *Player) applyFadeIn() {
+	if player.crossfade <= 0 {
+		return
+	}
+
+	player.fadeOutSet = false
+	player.setCrossfadeFilter(fmt.Sprintf("afade=t=in:st=0:d=%g", player.crossfade))
+}
+
+func (player *Player) applyFadeOut() {
+	duration := player.state.Duration
+	if player.crossfade <= 0 || player.fadeOutSet || duration <= 2*player.crossfade {
+		return
+	}
+
+	player.fadeOutSet = true
+	player.setCrossfadeFilter(fmt.Sprintf(
+		"afade=t=in:st=0:d=%g,afade=t=out:st=%g:d=%g",
+		player.crossfade,
+		duration-player.crossfade,
+		player.crossfade,
+	))
+}
+
+func (player *Player) setCrossfadeFilter(graph string) {
+	remove := map[string]any{"command": []string{"af", "remove", crossfadeLabel}}
+	add := map[string]any{"command": []string{"af", "add", crossfadeLabel + ":lavfi=[" + graph + "]"}}
+
+	if err := player.sendCommand(remove); err != nil {
+		log.Printf("failed to update crossfade filter: %s", err)
+		return
+	}
+	if err := player.sendCommand(add); err != nil {
+		log.Printf("failed to update crossfade filter: %s", err)
+	}
+}
+
+func (player *Player) handleEndFile(event mpvEvent) {
+	switch event.Reason {
+	case "eof":
+		log.Print("Track ended")
+		player.emit(Event{Type: EventTrackEnded})
+	case "error":
+		log.Printf("Playback error: %s", event.FileError)
+		player.emit(Event{Type: EventError, Message: event.FileError})
+		player.restoreFadeInVolume()
+	default:
+		return
+	}
+
+	player.state.Position = 0
+	player.publishState()
+}
+
+func parseFloat(data json.RawMessage) float64 {
+	var value float64
+	// Properties are null while nothing is loaded, which leaves value at zero.
+	_ = json.Unmarshal(data, &value)
+	return value
+}
+
+func parseBool(data json.RawMessage) bool {
+	var value bool
+	_ = json.Unmarshal(data, &value)
+	return value
+}
+
+func parseString(data json.RawMessage) string {
+	var value string
+	_ = json.Unmarshal(data, &value)
+	return value
+}
+
+func (player *Player) publishState() {
+	select {
+	case <-player.StateChanges:
+	default:
+	}
+	player.StateChanges <- player.state
+}
+
+// emit drops the event when nobody is draining Events, so a stalled
+// consumer cannot block the connection reader.
+func (player *Player) emit(event Event) {
+	select {
+	case player.Events <- event:
+	default:
+	}
 }
 
 func (player *Player) sendCommand(command map[string]any) error {
@@ -45,7 +551,17 @@ func (player *Player) sendCommand(command map[string]any) error {
 		return fmt.Errorf("failed to marshal mpv command: %s", err)
 	}
 
+	player.writeMutex.Lock()
+	defer player.writeMutex.Unlock()
+
+	if err := player.Conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return fmt.Errorf("failed to set write deadline: %s", err)
+	}
+
 	_, err = player.Conn.Write(append(json, '\n'))
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return fmt.Errorf("failed to write to connection: mpv did not respond within %s", writeTimeout)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to write to connection: %s", err)
 	}
@@ -53,25 +569,610 @@ func (player *Player) sendCommand(command map[string]any) error {
 	return nil
 }
 
+func (player *Player) setProperty(name string, value any) error {
+	command := map[string]any{"command": []any{"set_property", name, value}}
+	return player.sendCommand(command)
+}
+
+func (player *Player) getProperty(name string) (json.RawMessage, error) {
+	responseChan := make(chan mpvEvent, 1)
+
+	player.pendingMutex.Lock()
+	player.requestID++
+	id := player.requestID
+	player.pending[id] = responseChan
+	player.pendingMutex.Unlock()
+
+	command := map[string]any{"command": []string{"get_property", name}, "request_id": id}
+	if err := player.sendCommand(command); err != nil {
+		return nil, err
+	}
+
+	select {
+	case response := <-responseChan:
+		if response.Error != "success" {
+			return nil, fmt.Errorf("failed to get %s: %s", name, response.Error)
+		}
+		return response.Data, nil
+	case <-time.After(requestTimeout):
+		player.pendingMutex.Lock()
+		delete(player.pending, id)
+		player.pendingMutex.Unlock()
+
+		return nil, fmt.Errorf("timed out getting %s", name)
+	}
+}
+
 func (player *Player) LoadFile(path string) error {
+	if err := checkPath(path); err != nil {
+		return err
+	}
+
+	if player.fade > 0 {
+		player.prepareFadeIn()
+	}
+
 	command := map[string]any{"command": []string{"loadfile", path, "replace"}}
 	log.Print("Command sent: loadfile")
 
 	return player.sendCommand(command)
 }
 
+func (player *Player) LoadFileAt(path string, startSeconds float64) error {
+	if strings.Contains(path, "://") {
+		startSeconds = 0
+	}
+
+	player.SeekOnLoad(startSeconds)
+	if err := player.LoadFile(path); err != nil {
+		player.SeekOnLoad(0)
+		return err
+	}
+
+	return nil
+}
+
+func (player *Player) SeekOnLoad(seconds float64) {
+	player.pendingMutex.Lock()
+	player.startAt = seconds
+	player.pendingMutex.Unlock()
+}
+
+func (player *Player) AppendFile(path string) error {
+	if err := checkPath(path); err != nil {
+		return err
+	}
+
+	command := map[string]any{"command": []string{"loadfile", path, "append-play"}}
+	log.Print("Command sent: append")
+
+	return player.sendCommand(command)
+}
+
+func (player *Player) LoadPlaylist(paths []string, index int) error {
+	if err := player.sendCommand(map[string]any{"command": []string{"stop"}}); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if err := checkPath(path); err != nil {
+			return err
+		}
+
+		command := map[string]any{"command": []string{"loadfile", path, "append"}}
+		if err := player.sendCommand(command); err != nil {
+			return err
+		}
+	}
+	log.Print("Command sent: load playlist")
+
+	return player.PlayIndex(index)
+}
+
+func (player *Player) PlayIndex(index int) error {
+	command := map[string]any{"command": []any{"playlist-play-index", index}}
+	log.Print("Command sent: playlist-play-index")
+
+	return player.sendCommand(command)
+}
+
+func (player *Player) RemoveFromPlaylist(index int) error {
+	command := map[string]any{"command": []any{"playlist-remove", index}}
+	log.Print("Command sent: playlist-remove")
+
+	return player.sendCommand(command)
+}
+
+func (player *Player) MovePlaylistEntry(from int, to int) error {
+	target := to
+	if to > from {
+		target = to + 1
+	}
+
+	command := map[string]any{"command": []any{"playlist-move", from, target}}
+	log.Print("Command sent: playlist-move")
+
+	return player.sendCommand(command)
+}
+
+func checkPath(path string) error {
+	if strings.Contains(path, "://") {
+		return nil
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to access file: %s", err)
+	}
+
+	return nil
+}
+
 func (player *Player) TogglePause() error {
+	if player.fade > 0 {
+		return player.fadeTogglePause()
+	}
+
 	command := map[string]any{"command": []string{"cycle", "pause"}}
 	log.Print("Command sent: play/pause")
 
 	return player.sendCommand(command)
 }
 
+func (player *Player) Stop() error {
+	player.fadeMutex.Lock()
+	if player.cancelFade() {
+		if err := player.setProperty("volume", player.fadeVolume); err != nil {
+			log.Printf("failed to restore volume: %s", err)
+		}
+	}
+	player.fadeMutex.Unlock()
+
+	if err := player.setProperty("pause", false); err != nil {
+		return err
+	}
+
+	command := map[string]any{"command": []string{"stop"}}
+	log.Print("Command sent: stop")
+
+	return player.sendCommand(command)
+}
+
+func (player *Player) Status() (Status, error) {
+	idle, err := player.getProperty("idle-active")
+	if err != nil {
+		return StatusStopped, err
+	}
+	if parseBool(idle) {
+		return StatusStopped, nil
+	}
+
+	paused, err := player.getProperty("pause")
+	if err != nil {
+		return StatusStopped, err
+	}
+	if parseBool(paused) {
+		return StatusPaused, nil
+	}
+
+	return StatusPlaying, nil
+}
+
+func (player *Player) prepareFadeIn() {
+	volume, err := player.getFloatProperty("volume")
+	if err != nil || volume <= 0 {
+		return
+	}
+
+	if err := player.setProperty("volume", 0); err != nil {
+		log.Printf("failed to prepare fade-in: %s", err)
+		return
+	}
+
+	player.pendingMutex.Lock()
+	player.fadeInVolume = volume
+	player.pendingMutex.Unlock()
+}
+
+func (player *Player) restoreFadeInVolume() {
+	player.pendingMutex.Lock()
+	fadeInVolume := player.fadeInVolume
+	player.fadeInVolume = 0
+	player.pendingMutex.Unlock()
+
+	if fadeInVolume > 0 {
+		if err := player.setProperty("volume", fadeInVolume); err != nil {
+			log.Printf("failed to restore volume: %s", err)
+		}
+	}
+}
+
+// fadeTogglePause fades out before pausing and fades in after resuming. A
+// toggle during a running fade reverses it from the current volume, and
+// fadeVolume keeps the level from before the first fade so interrupted
+// fades cannot ratchet the volume down.
+func (player *Player) fadeTogglePause() error {
+	player.fadeMutex.Lock()
+	defer player.fadeMutex.Unlock()
+
+	fading := player.fading != nil
+	pausing := fading && !player.fading.pausing
+	player.cancelFade()
+
+	data, err := player.getProperty("pause")
+	if err != nil {
+		return fmt.Errorf("failed to fade: %s", err)
+	}
+
+	current, err := player.getFloatProperty("volume")
+	if err != nil {
+		return fmt.Errorf("failed to fade: %s", err)
+	}
+
+	if !fading {
+		pausing = !parseBool(data)
+		player.fadeVolume = current
+	}
+
+	log.Print("Command sent: play/pause")
+
+	target := player.fadeVolume
+	if pausing {
+		target = 0
+	} else {
+		if parseBool(data) {
+			current = 0
+			if err := player.setProperty("volume", 0); err != nil {
+				return fmt.Errorf("failed to fade: %s", err)
+			}
+		}
+		if err := player.setProperty("pause", false); err != nil {
+			return fmt.Errorf("failed to fade: %s", err)
+		}
+	}
+
+	fade := &pauseFade{pausing: pausing, cancel: make(chan struct{}), done: make(chan struct{})}
+	player.fading = fade
+
+	go func() {
+		completed := player.rampVolume(current, target, fade.cancel)
+		if completed && pausing {
+			if err := player.setProperty("pause", true); err != nil {
+				log.Printf("failed to pause: %s", err)
+			}
+			if err := player.setProperty("volume", player.fadeVolume); err != nil {
+				log.Printf("failed to restore volume: %s", err)
+			}
+		}
+		close(fade.done)
+
+		player.fadeMutex.Lock()
+		if player.fading == fade {
+			player.fading = nil
+		}
+		player.fadeMutex.Unlock()
+	}()
+
+	return nil
+}
+
+// cancelFade stops the running pause fade and waits for it to return. The
+// caller must hold fadeMutex.
+func (player *Player) cancelFade() bool {
+	fade := player.fading
+	if fade == nil {
+		return false
+	}
+
+	close(fade.cancel)
+	<-fade.done
+	player.fading = nil
+	return true
+}
+
+func (player *Player) rampVolume(from float64, to float64, cancel <-chan struct{}) bool {
+	for step := 1; step <= fadeSteps; step++ {
+		volume := from + (to-from)*float64(step)/fadeSteps
+		if err := player.setProperty("volume", volume); err != nil {
+			log.Printf("failed to fade volume: %s", err)
+			return false
+		}
+
+		select {
+		case <-cancel:
+			return false
+		case <-time.After(player.fade / fadeSteps):
+		}
+	}
+
+	return true
+}
+
+func (player *Player) getFloatProperty(name string) (float64, error) {
+	data, err := player.getProperty(name)
+	if err != nil {
+		return 0, err
+	}
+	return parseFloat(data), nil
+}
+
+func (player *Player) ChangeVolume(delta float64) error {
+	command := map[string]any{"command": []any{"add", "volume", delta}}
+	log.Print("Command sent: volume")
+
+	return player.sendCommand(command)
+}
+
+func (player *Player) SetVolume(volume float64) error {
+	log.Print("Command sent: volume")
+
+	return player.setProperty("volume", volume)
+}
+
+func (player *Player) ToggleMute() error {
+	command := map[string]any{"command": []string{"cycle", "mute"}}
+	log.Print("Command sent: mute")
+
+	return player.sendCommand(command)
+}
+
+func (player *Player) SetLoop(mode LoopMode) error {
+	loopFile, loopPlaylist := "no", "no"
+	switch mode {
+	case LoopTrack:
+		loopFile = "inf"
+	case LoopPlaylist:
+		loopPlaylist = "inf"
+	case LoopNone:
+	}
+
+	log.Print("Command sent: loop")
+
+	if err := player.setProperty("loop-file", loopFile); err != nil {
+		return err
+	}
+	return player.setProperty("loop-playlist", loopPlaylist)
+}
+
+func (player *Player) SetShuffle(enabled bool) error {
+	name := "playlist-unshuffle"
+	if enabled {
+		name = "playlist-shuffle"
+	}
+
+	command := map[string]any{"command": []string{name}}
+	log.Print("Command sent: " + name)
+
+	return player.sendCommand(command)
+}
+
+func ReplayGainMode(mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case ReplayGainTrack:
+		return ReplayGainTrack
+	case ReplayGainAlbum:
+		return ReplayGainAlbum
+	case "", ReplayGainOff, "no":
+		return ReplayGainOff
+	default:
+		log.Printf("Unknown replaygain mode %q, using %q", mode, ReplayGainOff)
+		return ReplayGainOff
+	}
+}
+
+func (player *Player) SetReplayGain(mode string) error {
+	value := ReplayGainMode(mode)
+	if value == ReplayGainOff {
+		value = "no"
+	}
+
+	log.Print("Command sent: replaygain")
+
+	return player.setProperty("replaygain", value)
+}
+
+func (player *Player) SetAudioDevice(name string) error {
+	log.Print("Command sent: audio-device")
+
+	return player.setProperty("audio-device", name)
+}
+
+func (player *Player) ListAudioDevices() ([]AudioDevice, error) {
+	data, err := player.getProperty("audio-device-list")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAudioDevices(data)
+}
+
+func parseAudioDevices(data json.RawMessage) ([]AudioDevice, error) {
+	var devices []AudioDevice
+	if err := json.Unmarshal(data, &devices); err != nil {
+		return nil, fmt.Errorf("failed to parse audio device list: %s", err)
+	}
+
+	return devices, nil
+}
+
+func (player *Player) SetGapless(enabled bool) error {
+	log.Print("Command sent: gapless-audio")
+
+	return player.setProperty("gapless-audio", yesNo(enabled))
+}
+
+func (player *Player) SetABLoop(a float64, b float64) error {
+	log.Print("Command sent: ab-loop")
+
+	if err := player.setProperty("ab-loop-a", loopPoint(a)); err != nil {
+		return err
+	}
+	return player.setProperty("ab-loop-b", loopPoint(b))
+}
+
+func (player *Player) ClearABLoop() error {
+	return player.SetABLoop(-1, -1)
+}
+
+func loopPoint(seconds float64) any {
+	if seconds < 0 {
+		return "no"
+	}
+	return seconds
+}
+
+func (player *Player) SetEqualizer(bands []float64) error {
+	filter := equalizerFilter(bands)
+	if filter == "" {
+		return player.ClearEqualizer()
+	}
+
+	if err := player.ClearEqualizer(); err != nil {
+		return err
+	}
+
+	command := map[string]any{"command": []string{"af", "add", filter}}
+	log.Print("Command sent: equalizer")
+
+	return player.sendCommand(command)
+}
+
+func (player *Player) ClearEqualizer() error {
+	command := map[string]any{"command": []string{"af", "remove", equalizerLabel}}
+	return player.sendCommand(command)
+}
+
+func equalizerFilter(bands []float64) string {
+	var filters []string
+
+	for i, gain := range bands {
+		if i >= len(equalizerFrequencies) {
+			break
+		}
+		if gain == 0 {
+			continue
+		}
+		filters = append(filters, fmt.Sprintf(
+			"equalizer=f=%d:width_type=o:width=1:g=%g",
+			equalizerFrequencies[i],
+			gain,
+		))
+	}
+
+	if len(filters) == 0 {
+		return ""
+	}
+
+	return equalizerLabel + ":lavfi=[" + strings.Join(filters, ",") + "]"
+}
+
+func (player *Player) SetAudioFilter(filter string) error {
+	if filter == "" {
+		return player.ClearAudioFilter()
+	}
+
+	if err := ValidateAudioFilter(filter); err != nil {
+		return err
+	}
+
+	if err := player.ClearAudioFilter(); err != nil {
+		return err
+	}
+
+	command := map[string]any{"command": []string{"af", "add", audioFilterLabel + ":" + filter}}
+	log.Print("Command sent: audio filter")
+
+	return player.sendCommand(command)
+}
+
+func (player *Player) ClearAudioFilter() error {
+	command := map[string]any{"command": []string{"af", "remove", audioFilterLabel}}
+	return player.sendCommand(command)
+}
+
+// ValidateAudioFilter checks a single mpv --af entry of the form
+// name[=key=value[:key=value...]], where lavfi graphs are wrapped in
+// brackets, e.g. "lavfi=[bass=g=6,treble=g=2]". Chains of several mpv
+// filters and labels are rejected since the player labels the entry itself.
+func ValidateAudioFilter(filter string) error {
+	name, _, _ := strings.Cut(filter, "=")
+	if name == "" {
+		return fmt.Errorf("invalid audio filter %q: missing filter name", filter)
+	}
+	for _, char := range name {
+		if !(char >= 'a' && char <= 'z' || char >= '0' && char <= '9' || char == '_' || char == '-') {
+			return fmt.Errorf("invalid audio filter %q: bad filter name", filter)
+		}
+	}
+
+	depth := 0
+	for _, char := range filter {
+		switch {
+		case char == '[':
+			depth++
+		case char == ']':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("invalid audio filter %q: unbalanced brackets", filter)
+			}
+		case char == ',' && depth == 0:
+			return fmt.Errorf("invalid audio filter %q: only one filter is allowed, use lavfi=[...] for chains", filter)
+		case char < ' ' || char == ' ' && depth == 0:
+			return fmt.Errorf("invalid audio filter %q: unexpected whitespace", filter)
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("invalid audio filter %q: unbalanced brackets", filter)
+	}
+
+	return nil
+}
+
+func (player *Player) SeekRelative(seconds float64) error {
+	return player.seek(seconds, "relative")
+}
+
+func (player *Player) SeekAbsolute(seconds float64) error {
+	return player.seek(max(seconds, 0), "absolute")
+}
+
+func (player *Player) seek(seconds float64, flag string) error {
+	command := map[string]any{"command": []any{"seek", seconds, flag}}
+	log.Print("Command sent: seek")
+
+	return player.sendCommand(command)
+}
+
 func (player *Player) Close() {
+	exited := make(chan struct{})
+	go func() {
+		// Wait closes the stderr pipe, so the reader has to see EOF first.
+		if player.outputDone != nil {
+			<-player.outputDone
+		}
+		if err := player.cmd.Wait(); err != nil {
+			log.Printf("mpv exited: %s", err)
+		}
+		close(exited)
+	}()
+
+	command := map[string]any{"command": []string{"quit"}}
+	if err := player.sendCommand(command); err != nil {
+		log.Printf("failed to send quit: %s", err)
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(quitTimeout):
+		if err := player.cmd.Process.Kill(); err != nil {
+			log.Printf("failed to kill mpv process: %s", err)
+		}
+		<-exited
+	}
+
 	if err := player.Conn.Close(); err != nil {
 		log.Printf("failed to close connection: %s", err)
 	}
-	if err := player.cmd.Process.Kill(); err != nil {
-		log.Printf("failed to kill mpv process: %s", err)
+	if err := os.Remove(player.SocketPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to remove mpv socket: %s", err)
 	}
 }
@@ -0,0 +1,230 @@
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type fakeMpv struct {
+	conn     net.Conn
+	commands chan []any
+}
+
+func newPipePlayer(t *testing.T) (*Player, *fakeMpv) {
+	t.Helper()
+
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+
+	player := &Player{
+		Conn:         client,
+		StateChanges: make(chan State, 1),
+		Events:       make(chan Event, eventBufferSize),
+		pending:      make(map[int]chan mpvEvent),
+	}
+	go player.readEvents()
+
+	mpv := &fakeMpv{conn: server, commands: make(chan []any, 16)}
+	go func() {
+		scanner := bufio.NewScanner(server)
+		for scanner.Scan() {
+			var request struct {
+				Command []any `json:"command"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &request); err == nil {
+				mpv.commands <- request.Command
+			}
+		}
+	}()
+
+	return player, mpv
+}
+
+func (mpv *fakeMpv) next(t *testing.T) []any {
+	t.Helper()
+
+	select {
+	case command := <-mpv.commands:
+		return command
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a command")
+		return nil
+	}
+}
+
+func (mpv *fakeMpv) send(t *testing.T, event string) {
+	t.Helper()
+
+	if _, err := mpv.conn.Write([]byte(event + "\n")); err != nil {
+		t.Fatalf("send %s: %v", event, err)
+	}
+}
+
+func tempTrack(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "track.mp3")
+	if err := os.WriteFile(path, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFileAtSeeksAfterLoad(t *testing.T) {
+	player, mpv := newPipePlayer(t)
+	path := tempTrack(t)
+
+	if err := player.LoadFileAt(path, 42); err != nil {
+		t.Fatalf("LoadFileAt: %v", err)
+	}
+
+	if command := mpv.next(t); command[0] != "loadfile" || command[1] != path {
+		t.Fatalf("first command = %v, want loadfile %s", command, path)
+	}
+
+	mpv.send(t, `{"event":"file-loaded"}`)
+
+	command := mpv.next(t)
+	if command[0] != "seek" || command[1] != 42.0 || command[2] != "absolute" {
+		t.Fatalf("command after load = %v, want seek 42 absolute", command)
+	}
+}
+
+func TestLoadFileAtIgnoresStartForStreams(t *testing.T) {
+	player, mpv := newPipePlayer(t)
+	url := "https://example.com/stream.aac"
+
+	if err := player.LoadFileAt(url, 42); err != nil {
+		t.Fatalf("LoadFileAt: %v", err)
+	}
+	if player.startAt != 0 {
+		t.Errorf("start position = %g, want 0 for a stream", player.startAt)
+	}
+
+	if command := mpv.next(t); command[0] != "loadfile" || command[1] != url {
+		t.Fatalf("first command = %v, want loadfile %s", command, url)
+	}
+
+	mpv.send(t, `{"event":"file-loaded"}`)
+	mpv.send(t, `{"event":"property-change","name":"volume","data":100}`)
+	<-player.StateChanges
+	if err := player.SetVolume(50); err != nil {
+		t.Fatalf("SetVolume: %v", err)
+	}
+
+	if command := mpv.next(t); command[0] != "set_property" {
+		t.Fatalf("command after load = %v, want no seek", command)
+	}
+}
+
+func TestLoadFileAtResetsStartOnError(t *testing.T) {
+	player, _ := newPipePlayer(t)
+
+	if err := player.LoadFileAt(filepath.Join(t.TempDir(), "missing.mp3"), 42); err == nil {
+		t.Fatal("LoadFileAt of a missing file succeeded")
+	}
+	if player.startAt != 0 {
+		t.Errorf("start position = %g after a failed load, want 0", player.startAt)
+	}
+}
+
+func TestParseAudioDevices(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []AudioDevice
+		wantErr bool
+	}{
+		{
+			name: "pulse and alsa",
+			payload: `[
+				{"name":"auto","description":"Autoselect device"},
+				{"name":"pulse","description":"Default (pulse)"},
+				{"name":"pulse/alsa_output.pci-0000_00_1f.3.analog-stereo","description":"Built-in Audio Analog Stereo"},
+				{"name":"alsa/hdmi:CARD=HDMI,DEV=0","description":"HDA Intel HDMI/DP, HDMI 0"}
+			]`,
+			want: []AudioDevice{
+				{Name: "auto", Description: "Autoselect device"},
+				{Name: "pulse", Description: "Default (pulse)"},
+				{Name: "pulse/alsa_output.pci-0000_00_1f.3.analog-stereo", Description: "Built-in Audio Analog Stereo"},
+				{Name: "alsa/hdmi:CARD=HDMI,DEV=0", Description: "HDA Intel HDMI/DP, HDMI 0"},
+			},
+		},
+		{
+			name:    "unicode description",
+			payload: `[{"name":"pipewire/bluez_output.00_1B_66","description":"Kopfhörer ä"}]`,
+			want:    []AudioDevice{{Name: "pipewire/bluez_output.00_1B_66", Description: "Kopfhörer ä"}},
+		},
+		{name: "extra fields", payload: `[{"name":"auto","description":"Autoselect device","driver":"x"}]`, want: []AudioDevice{{Name: "auto", Description: "Autoselect device"}}},
+		{name: "empty", payload: `[]`, want: []AudioDevice{}},
+		{name: "null", payload: `null`, want: nil},
+		{name: "not a list", payload: `{"name":"auto"}`, wantErr: true},
+		{name: "truncated", payload: `[{"name":"auto"`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			devices, err := parseAudioDevices(json.RawMessage(test.payload))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parse error = %v, want error %t", err, test.wantErr)
+			}
+			if !slices.Equal(devices, test.want) {
+				t.Errorf("parse = %+v, want %+v", devices, test.want)
+			}
+		})
+	}
+}
+
+func TestNewPlayerCleansUpWhenConnectFails(t *testing.T) {
+	bin, runtime := t.TempDir(), t.TempDir()
+	pidFile := filepath.Join(t.TempDir(), "mpv.pid")
+
+	// The fake mpv leaves a plain file where the socket should be and never listens.
+	script := `#!/bin/sh
+for arg; do
+	case "$arg" in
+	--input-ipc-server=*) : > "${arg#--input-ipc-server=}" ;;
+	esac
+done
+echo $$ > "` + pidFile + `"
+exec sleep 30
+`
+	if err := os.WriteFile(filepath.Join(bin, "mpv"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("XDG_RUNTIME_DIR", runtime)
+
+	if _, err := NewPlayer(Options{}); err == nil {
+		t.Fatal("NewPlayer without an mpv socket succeeded")
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("fake mpv did not start: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(pid, 0); !errors.Is(err, syscall.ESRCH) {
+		t.Errorf("mpv process %d is still around after a failed start: %v", pid, err)
+	}
+
+	if sockets, _ := filepath.Glob(filepath.Join(SocketDir(), socketPattern)); len(sockets) != 0 {
+		t.Errorf("failed start left sockets behind: %v", sockets)
+	}
+}
@@ -0,0 +1,78 @@
+package resume
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const minPosition = 5.0
+const endMargin = 10.0
+
+type Store struct {
+	path      string
+	positions map[string]float64
+}
+
+func LoadStore() (*Store, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user cache directory: %s", err)
+	}
+
+	store := &Store{
+		path:      filepath.Join(cacheDir, "tunecli", "positions.json"),
+		positions: make(map[string]float64),
+	}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read positions file: %s", err)
+	}
+
+	if err := json.Unmarshal(data, &store.positions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal positions: %s", err)
+	}
+
+	return store, nil
+}
+
+func (store *Store) Get(path string) (float64, bool) {
+	position, ok := store.positions[path]
+	return position, ok
+}
+
+func (store *Store) Set(path string, position float64, duration float64) {
+	if strings.Contains(path, "://") {
+		return
+	}
+
+	if position < minPosition || (duration > 0 && position > duration-endMargin) {
+		delete(store.positions, path)
+		return
+	}
+
+	store.positions[path] = position
+}
+
+func (store *Store) Save() error {
+	data, err := json.Marshal(store.positions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal positions: %s", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(store.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %s", err)
+	}
+
+	if err := os.WriteFile(store.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write positions file: %s", err)
+	}
+
+	return nil
+}
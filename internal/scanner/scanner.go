@@ -0,0 +1,462 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type MusicFile struct {
+	Path     string    `json:"path"`
+	Name     string    `json:"name"`
+	Format   string    `json:"format"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	Duration float64   `json:"duration,omitempty"`
+	Codec    string    `json:"codec,omitempty"`
+	Album    string    `json:"album"`
+	Artist   string    `json:"artist"`
+}
+
+type Directory struct {
+	Path      string
+	Recursive bool
+}
+
+type Options struct {
+	FollowSymlinks  bool
+	IncludeHidden   bool
+	ExcludePatterns []string
+	ProbeDurations  bool
+}
+
+type probeCacheEntry struct {
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	Duration float64   `json:"duration"`
+	Codec    string    `json:"codec"`
+}
+
+type SortMode uint8
+
+const (
+	SortPath SortMode = iota
+	SortName
+	SortModified
+	SortSize
+)
+
+type GroupMode uint8
+
+const (
+	GroupNone GroupMode = iota
+	GroupAlbum
+	GroupArtist
+)
+
+var audioExts = map[string]string{
+	".aac":  "audio/aac",
+	".aiff": "audio/aiff",
+	".alac": "audio/mp4",
+	".ape":  "audio/x-ape",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".mka":  "audio/x-matroska",
+	".mp3":  "audio/mpeg",
+	".ogg":  "audio/ogg",
+	".opus": "audio/opus",
+	".wav":  "audio/wav",
+	".wma":  "audio/x-ms-wma",
+	".wv":   "audio/x-wavpack",
+}
+
+var DefaultIgnore = []string{".DS_Store", "._*", "@eaDir", ".git", ".stfolder"}
+
+const probeTimeout = 10 * time.Second
+const probePrefix = "TUNECLI_PROBE:"
+const probeWorkers = 4
+const maxReportedErrors = 5
+
+func ScanDirectories(dirs []Directory, options Options) ([]MusicFile, error) {
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no music dirs provided")
+	}
+
+	for _, pattern := range options.ExcludePatterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %s", pattern, err)
+		}
+	}
+
+	state := &scanState{options: options, visited: make(map[string]bool)}
+	for _, dir := range dirs {
+		if err := state.scanDirectory(dir.Path, dir.Recursive); err != nil {
+			return nil, err
+		}
+	}
+	state.reportErrors()
+
+	files := uniqueFiles(state.files)
+	Sort(files, GroupNone, SortPath)
+
+	if options.ProbeDurations {
+		probeFiles(files)
+	}
+
+	return files, nil
+}
+
+type scanState struct {
+	options Options
+	visited map[string]bool
+	files   []MusicFile
+	errors  []error
+}
+
+func (state *scanState) reportErrors() {
+	if len(state.errors) == 0 {
+		return
+	}
+
+	for _, err := range state.errors[:min(len(state.errors), maxReportedErrors)] {
+		log.Printf("Failed to scan: %v", err)
+	}
+	log.Printf("Skipped %d unreadable paths while scanning", len(state.errors))
+}
+
+func uniqueFiles(files []MusicFile) []MusicFile {
+	seen := make(map[string]bool, len(files))
+	unique := files[:0]
+
+	for _, file := range files {
+		key, err := filepath.Abs(file.Path)
+		if err != nil {
+			key = file.Path
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, file)
+	}
+
+	return unique
+}
+
+// scanDirectory fails only when dir itself cannot be read. Unreadable paths
+// below it are collected and reported once the scan finishes.
+func (state *scanState) scanDirectory(dir string, recursive bool) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil && path == dir {
+			return fmt.Errorf("failed to read directory: %s", err)
+		}
+		if err != nil {
+			state.errors = append(state.errors, err)
+			return nil
+		}
+
+		if path != dir && state.options.Excluded(path, entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			if state.options.FollowSymlinks {
+				realPath, err := filepath.EvalSymlinks(path)
+				if err != nil {
+					state.errors = append(state.errors, err)
+					return filepath.SkipDir
+				}
+				if state.visited[realPath] {
+					return filepath.SkipDir
+				}
+				state.visited[realPath] = true
+			}
+			return nil
+		}
+
+		if state.options.FollowSymlinks && entry.Type()&fs.ModeSymlink != 0 {
+			state.scanSymlink(path, recursive)
+			return nil
+		}
+
+		if !IsAudioFile(path) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			state.errors = append(state.errors, err)
+			return nil
+		}
+
+		state.files = append(state.files, newMusicFile(path, info))
+		return nil
+	})
+}
+
+func (options Options) Excluded(path string, name string) bool {
+	if !options.IncludeHidden && strings.HasPrefix(name, ".") {
+		return true
+	}
+
+	for _, pattern := range options.ExcludePatterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, path); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (state *scanState) scanSymlink(path string, recursive bool) {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		state.errors = append(state.errors, fmt.Errorf("failed to resolve symlink %s: %s", path, err))
+		return
+	}
+
+	info, err := os.Stat(realPath)
+	if err != nil {
+		state.errors = append(state.errors, err)
+		return
+	}
+
+	if info.IsDir() {
+		if recursive {
+			if err := state.scanDirectory(realPath, recursive); err != nil {
+				state.errors = append(state.errors, err)
+			}
+		}
+		return
+	}
+
+	if IsAudioFile(path) {
+		state.files = append(state.files, newMusicFile(path, info))
+	}
+}
+
+func IsAudioFile(path string) bool {
+	_, ok := audioExts[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+func MimeTypes() []string {
+	mimeTypes := slices.Collect(maps.Values(audioExts))
+	slices.Sort(mimeTypes)
+	return slices.Compact(mimeTypes)
+}
+
+func newMusicFile(path string, info os.FileInfo) MusicFile {
+	albumDir := filepath.Dir(path)
+
+	return MusicFile{
+		Path:    path,
+		Name:    info.Name(),
+		Format:  strings.ToUpper(strings.TrimPrefix(filepath.Ext(path), ".")),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Album:   filepath.Base(albumDir),
+		Artist:  filepath.Base(filepath.Dir(albumDir)),
+	}
+}
+
+func GroupKey(file MusicFile, mode GroupMode) string {
+	switch mode {
+	case GroupAlbum:
+		return file.Artist + " - " + file.Album
+	case GroupArtist:
+		return file.Artist
+	}
+
+	return ""
+}
+
+func Sort(files []MusicFile, group GroupMode, order SortMode) {
+	sort.SliceStable(files, func(i, j int) bool {
+		keyI, keyJ := GroupKey(files[i], group), GroupKey(files[j], group)
+		if keyI != keyJ {
+			return keyI < keyJ
+		}
+
+		switch order {
+		case SortName:
+			nameI, nameJ := strings.ToLower(files[i].Name), strings.ToLower(files[j].Name)
+			if nameI != nameJ {
+				return nameI < nameJ
+			}
+		case SortModified:
+			if !files[i].ModTime.Equal(files[j].ModTime) {
+				return files[i].ModTime.After(files[j].ModTime)
+			}
+		case SortSize:
+			if files[i].Size != files[j].Size {
+				return files[i].Size > files[j].Size
+			}
+		}
+		return files[i].Path < files[j].Path
+	})
+}
+
+func probeFiles(files []MusicFile) {
+	cache, err := loadProbeCache()
+	if err != nil {
+		log.Printf("Failed to load probe cache: %v", err)
+	}
+
+	// Entries for files outside this scan are kept, so scanning one music dir
+	// does not drop the cached durations of the others.
+	if cache == nil {
+		cache = make(map[string]probeCacheEntry)
+	}
+
+	probed := make(map[string]probeCacheEntry)
+	var mutex sync.Mutex
+	var wait sync.WaitGroup
+	workers := make(chan struct{}, probeWorkers)
+
+	for i := range files {
+		file := &files[i]
+
+		entry, ok := cache[file.Path]
+		if ok && entry.Size == file.Size && entry.ModTime.Equal(file.ModTime) {
+			file.Duration, file.Codec = entry.Duration, entry.Codec
+			continue
+		}
+
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			duration, codec, err := Probe(file.Path)
+			if err != nil {
+				return
+			}
+			file.Duration, file.Codec = duration, codec
+
+			mutex.Lock()
+			probed[file.Path] = probeCacheEntry{file.Size, file.ModTime, duration, codec}
+			mutex.Unlock()
+		}()
+	}
+	wait.Wait()
+
+	if len(probed) == 0 {
+		return
+	}
+	maps.Copy(cache, probed)
+
+	if err := saveProbeCache(cache); err != nil {
+		log.Printf("Failed to save probe cache: %v", err)
+	}
+}
+
+func probeCachePath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user cache directory: %s", err)
+	}
+
+	return filepath.Join(cacheDir, "tunecli", "durations.json"), nil
+}
+
+func loadProbeCache() (map[string]probeCacheEntry, error) {
+	path, err := probeCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read probe cache: %s", err)
+	}
+
+	var cache map[string]probeCacheEntry
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal probe cache: %s", err)
+	}
+
+	return cache, nil
+}
+
+func saveProbeCache(cache map[string]probeCacheEntry) error {
+	path, err := probeCachePath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("failed to marshal probe cache: %s", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %s", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write probe cache: %s", err)
+	}
+
+	return nil
+}
+
+func Probe(path string) (float64, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "mpv",
+		"--no-config",
+		"--no-video",
+		"--ao=null",
+		"--end=0.01",
+		"--msg-level=all=no,cplayer=info",
+		"--term-playing-msg="+probePrefix+"${=duration}|${audio-codec-name}",
+		path,
+	).Output()
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to probe file: %s", err)
+	}
+
+	for line := range strings.SplitSeq(string(output), "\n") {
+		fields, ok := strings.CutPrefix(strings.TrimSpace(line), probePrefix)
+		if !ok {
+			continue
+		}
+
+		durationText, codec, _ := strings.Cut(fields, "|")
+		duration, err := strconv.ParseFloat(durationText, 64)
+		if err != nil {
+			return 0, codec, fmt.Errorf("failed to parse duration: %s", err)
+		}
+
+		return duration, codec, nil
+	}
+
+	return 0, "", fmt.Errorf("failed to probe file: no playback info")
+}
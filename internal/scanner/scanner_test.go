@@ -0,0 +1,197 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, root string, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("audio"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanDirectoriesOverlapping(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, "b.mp3", "album/a.flac", "album/c.ogg", "album/notes.txt")
+
+	dirs := []Directory{
+		{Path: filepath.Join(root, "album"), Recursive: true},
+		{Path: root, Recursive: true},
+	}
+	files, err := ScanDirectories(dirs, Options{})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+
+	want := []string{
+		filepath.Join(root, "album", "a.flac"),
+		filepath.Join(root, "album", "c.ogg"),
+		filepath.Join(root, "b.mp3"),
+	}
+	if !slices.Equal(paths, want) {
+		t.Errorf("scan = %v, want %v", paths, want)
+	}
+}
+
+func writeSilentWav(t *testing.T, path string, seconds int) {
+	t.Helper()
+
+	const sampleRate = 8000
+	samples := make([]byte, sampleRate*2*seconds)
+
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+len(samples)))
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1)
+	binary.LittleEndian.PutUint16(header[22:], 1)
+	binary.LittleEndian.PutUint32(header[24:], sampleRate)
+	binary.LittleEndian.PutUint32(header[28:], sampleRate*2)
+	binary.LittleEndian.PutUint16(header[32:], 2)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(len(samples)))
+
+	if err := os.WriteFile(path, append(header, samples...), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProbeKnownDuration(t *testing.T) {
+	if _, err := exec.LookPath("mpv"); err != nil {
+		t.Skip("mpv is not installed")
+	}
+
+	path := filepath.Join(t.TempDir(), "silence.wav")
+	writeSilentWav(t, path, 2)
+
+	duration, codec, err := Probe(path)
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if math.Abs(duration-2) > 0.05 {
+		t.Errorf("probe duration = %g, want 2", duration)
+	}
+	if codec == "" {
+		t.Error("probe returned no codec")
+	}
+}
+
+func TestProbeCacheInvalidation(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "track.mp3")
+	writeFiles(t, filepath.Dir(path), "track.mp3")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cached := probeCacheEntry{Size: info.Size(), ModTime: info.ModTime(), Duration: 123, Codec: "mp3"}
+	if err := saveProbeCache(map[string]probeCacheEntry{path: cached}); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []MusicFile{newMusicFile(path, info)}
+	probeFiles(files)
+	if files[0].Duration != 123 || files[0].Codec != "mp3" {
+		t.Fatalf("unchanged file = %g %q, want cached 123 mp3", files[0].Duration, files[0].Codec)
+	}
+
+	tests := []struct {
+		name   string
+		change func(file *MusicFile)
+	}{
+		{"size", func(file *MusicFile) { file.Size++ }},
+		{"modtime", func(file *MusicFile) { file.ModTime = file.ModTime.Add(time.Second) }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := saveProbeCache(map[string]probeCacheEntry{path: cached}); err != nil {
+				t.Fatal(err)
+			}
+
+			files := []MusicFile{newMusicFile(path, info)}
+			test.change(&files[0])
+			probeFiles(files)
+
+			// The fixture is not real audio, so a fresh probe finds no duration.
+			if files[0].Duration == 123 {
+				t.Errorf("changed %s still used the cached duration", test.name)
+			}
+		})
+	}
+}
+
+func TestScanDirectoriesMissingDirectory(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, "a.mp3")
+
+	dirs := []Directory{{Path: root, Recursive: true}, {Path: filepath.Join(root, "missing"), Recursive: true}}
+	if _, err := ScanDirectories(dirs, Options{}); err == nil {
+		t.Fatal("scan of a missing music dir succeeded")
+	}
+}
+
+// installFakeMpv puts an mpv on PATH that reports every file as one second of PCM.
+func installFakeMpv(t *testing.T) {
+	t.Helper()
+
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho '" + probePrefix + "1.000000|pcm_s16le'\n"
+	if err := os.WriteFile(filepath.Join(bin, "mpv"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestProbeCacheKeepsOtherEntries(t *testing.T) {
+	installFakeMpv(t)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	other := probeCacheEntry{Size: 5, ModTime: time.Unix(1700000000, 0), Duration: 123, Codec: "mp3"}
+	if err := saveProbeCache(map[string]probeCacheEntry{"/elsewhere/track.mp3": other}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "track.mp3")
+	writeFiles(t, filepath.Dir(path), "track.mp3")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	probeFiles([]MusicFile{newMusicFile(path, info)})
+
+	cache, err := loadProbeCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, ok := cache["/elsewhere/track.mp3"]; !ok || entry.Duration != other.Duration {
+		t.Errorf("cache lost the entry of a file outside the scan: %+v", cache)
+	}
+	if _, ok := cache[path]; !ok {
+		t.Errorf("cache has no entry for the probed file: %+v", cache)
+	}
+}
@@ -0,0 +1,85 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sokolawesome/tunecli/internal/player"
+)
+
+type Session struct {
+	Queue    []string        `json:"queue"`
+	Index    int             `json:"index"`
+	Position float64         `json:"position"`
+	Volume   float64         `json:"volume"`
+	Loop     player.LoopMode `json:"loop"`
+	Shuffle  bool            `json:"shuffle"`
+}
+
+func sessionPath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user cache directory: %s", err)
+	}
+
+	return filepath.Join(cacheDir, "tunecli", "session.json"), nil
+}
+
+func Load() (*Session, error) {
+	path, err := sessionPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read session file: %s", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %s", err)
+	}
+
+	return &session, nil
+}
+
+func (session *Session) Save() error {
+	path, err := sessionPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %s", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %s", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write session file: %s", err)
+	}
+
+	return nil
+}
+
+func Clear() error {
+	path, err := sessionPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove session file: %s", err)
+	}
+
+	return nil
+}
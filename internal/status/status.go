@@ -0,0 +1,115 @@
+package status
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/sokolawesome/tunecli/internal/timefmt"
+)
+
+type Status struct {
+	Status   string  `json:"status"`
+	Title    string  `json:"title"`
+	Position float64 `json:"position"`
+	Duration float64 `json:"duration"`
+}
+
+type response struct {
+	Event     string          `json:"event"`
+	RequestID int             `json:"request_id"`
+	Data      json.RawMessage `json:"data"`
+}
+
+const queryTimeout = 2 * time.Second
+
+var properties = []string{"idle-active", "pause", "media-title", "time-pos", "duration"}
+
+// QueryAny returns the status of the first instance that answers, skipping
+// sockets left behind by instances that exited without cleaning up.
+func QueryAny(socketPaths []string) (*Status, error) {
+	if len(socketPaths) == 0 {
+		return nil, fmt.Errorf("tunecli is not running")
+	}
+
+	var err error
+	for _, socketPath := range socketPaths {
+		var status *Status
+		if status, err = Query(socketPath); err == nil {
+			return status, nil
+		}
+	}
+
+	return nil, err
+}
+
+func Query(socketPath string) (*Status, error) {
+	conn, err := net.DialTimeout("unix", socketPath, queryTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("tunecli is not running: %s", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(queryTimeout)); err != nil {
+		return nil, fmt.Errorf("failed to set deadline: %s", err)
+	}
+
+	for i, name := range properties {
+		command, err := json.Marshal(map[string]any{
+			"command":    []string{"get_property", name},
+			"request_id": i + 1,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal mpv command: %s", err)
+		}
+		if _, err := conn.Write(append(command, '\n')); err != nil {
+			return nil, fmt.Errorf("failed to write to connection: %s", err)
+		}
+	}
+
+	values := make(map[string]json.RawMessage)
+	scanner := bufio.NewScanner(conn)
+	for len(values) < len(properties) && scanner.Scan() {
+		var response response
+		if err := json.Unmarshal(scanner.Bytes(), &response); err != nil {
+			continue
+		}
+		if response.Event != "" || response.RequestID < 1 || response.RequestID > len(properties) {
+			continue
+		}
+		values[properties[response.RequestID-1]] = response.Data
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read from connection: %s", err)
+	}
+	if len(values) < len(properties) {
+		return nil, fmt.Errorf("failed to read status: connection closed")
+	}
+
+	var idle, paused bool
+	status := &Status{Status: "Playing"}
+	json.Unmarshal(values["idle-active"], &idle)
+	json.Unmarshal(values["pause"], &paused)
+	json.Unmarshal(values["media-title"], &status.Title)
+	json.Unmarshal(values["time-pos"], &status.Position)
+	json.Unmarshal(values["duration"], &status.Duration)
+
+	switch {
+	case idle:
+		*status = Status{Status: "Stopped"}
+	case paused:
+		status.Status = "Paused"
+	}
+
+	return status, nil
+}
+
+func (status *Status) String() string {
+	if status.Status == "Stopped" {
+		return status.Status
+	}
+
+	return fmt.Sprintf("%s: %s [%s]", status.Status, status.Title, timefmt.Progress(status.Position, status.Duration))
+}
@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const fetchTimeout = 10 * time.Second
+const maxPlaylistSize = 64 * 1024
+
+var client = &http.Client{Timeout: fetchTimeout}
+
+var (
+	cache      = make(map[string]string)
+	cacheMutex sync.Mutex
+)
+
+func IsPlaylist(location string) bool {
+	parsed, err := url.Parse(location)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return false
+	}
+
+	switch strings.ToLower(path.Ext(parsed.Path)) {
+	case ".pls", ".m3u":
+		return true
+	}
+	return false
+}
+
+func Lookup(location string) (string, bool) {
+	if !IsPlaylist(location) {
+		return location, true
+	}
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	resolved, ok := cache[location]
+	return resolved, ok
+}
+
+func Resolve(location string) (string, error) {
+	if resolved, ok := Lookup(location); ok {
+		return resolved, nil
+	}
+
+	resolved, err := fetch(location)
+	if err != nil {
+		return "", err
+	}
+
+	cacheMutex.Lock()
+	cache[location] = resolved
+	cacheMutex.Unlock()
+
+	return resolved, nil
+}
+
+func fetch(location string) (string, error) {
+	response, err := client.Get(location)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch playlist: %s", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch playlist: %s", response.Status)
+	}
+
+	entry, err := firstEntry(io.LimitReader(response.Body, maxPlaylistSize))
+	if err != nil {
+		return "", err
+	}
+
+	base, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse playlist url: %s", err)
+	}
+	reference, err := url.Parse(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse playlist entry: %s", err)
+	}
+
+	return base.ResolveReference(reference).String(), nil
+}
+
+func firstEntry(body io.Reader) (string, error) {
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.Contains(key, "/") {
+			return line, nil
+		}
+		if strings.HasPrefix(strings.ToLower(key), "file") && value != "" {
+			return strings.TrimSpace(value), nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read playlist: %s", err)
+	}
+	return "", fmt.Errorf("playlist has no entries")
+}
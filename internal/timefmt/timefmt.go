@@ -0,0 +1,33 @@
+package timefmt
+
+import (
+	"fmt"
+	"math"
+)
+
+const Unknown = "--:--"
+
+const maxSeconds = math.MaxInt32
+
+func Duration(seconds float64) string {
+	if math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return Unknown
+	}
+
+	total := int64(min(max(seconds, 0), maxSeconds))
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total%3600/60, total%60)
+	}
+	return fmt.Sprintf("%02d:%02d", total/60, total%60)
+}
+
+func Progress(position float64, duration float64) string {
+	if duration <= 0 || math.IsNaN(duration) || math.IsInf(duration, 0) {
+		return fmt.Sprintf("%s / %s", Duration(position), Unknown)
+	}
+	if math.IsNaN(position) || math.IsInf(position, 0) {
+		return fmt.Sprintf("%s / %s", Unknown, Duration(duration))
+	}
+
+	return fmt.Sprintf("%s / %s (-%s)", Duration(position), Duration(duration), Duration(duration-position))
+}
@@ -0,0 +1,56 @@
+package timefmt
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "00:00"},
+		{59.9, "00:59"},
+		{61, "01:01"},
+		{3599, "59:59"},
+		{3600, "1:00:00"},
+		{3725, "1:02:05"},
+		{100 * 3600, "100:00:00"},
+		{1e12, "596523:14:07"},
+		{-1, "00:00"},
+		{math.Inf(-1), Unknown},
+		{math.Inf(1), Unknown},
+		{math.NaN(), Unknown},
+	}
+
+	for _, test := range tests {
+		if got := Duration(test.seconds); got != test.want {
+			t.Errorf("Duration(%g) = %q, want %q", test.seconds, got, test.want)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	tests := []struct {
+		position float64
+		duration float64
+		want     string
+	}{
+		{30, 90, "00:30 / 01:30 (-01:00)"},
+		{30, 0, "00:30 / --:--"},
+		{30, -1, "00:30 / --:--"},
+		{30, math.NaN(), "00:30 / --:--"},
+		{30, math.Inf(1), "00:30 / --:--"},
+		{math.NaN(), 90, "--:-- / 01:30"},
+		{math.Inf(1), 90, "--:-- / 01:30"},
+		{100, 90, "01:40 / 01:30 (-00:00)"},
+		{3600, 7200, "1:00:00 / 2:00:00 (-1:00:00)"},
+	}
+
+	for _, test := range tests {
+		if got := Progress(test.position, test.duration); got != test.want {
+			t.Errorf("Progress(%g, %g) = %q, want %q", test.position, test.duration, got, test.want)
+		}
+	}
+}
@@ -3,42 +3,267 @@ package ui
 import (
 	"fmt"
 	"log"
+	"math"
+	"math/rand/v2"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/sokolawesome/tunecli/internal/alarm"
 	"github.com/sokolawesome/tunecli/internal/config"
+	"github.com/sokolawesome/tunecli/internal/favorites"
+	"github.com/sokolawesome/tunecli/internal/fuzzy"
+	"github.com/sokolawesome/tunecli/internal/history"
 	"github.com/sokolawesome/tunecli/internal/mpris"
+	"github.com/sokolawesome/tunecli/internal/notify"
 	"github.com/sokolawesome/tunecli/internal/player"
+	"github.com/sokolawesome/tunecli/internal/resume"
+	"github.com/sokolawesome/tunecli/internal/scanner"
+	"github.com/sokolawesome/tunecli/internal/session"
+	"github.com/sokolawesome/tunecli/internal/stream"
+	"github.com/sokolawesome/tunecli/internal/timefmt"
+	"github.com/sokolawesome/tunecli/internal/watcher"
 )
 
+const defaultTheme = "default"
+
+var themes = map[string]config.Theme{
+	"default": {
+		Selected: "205",
+		Border:   "80",
+		Accent:   "39",
+		Playing:  "42",
+		Paused:   "214",
+		Stopped:  "245",
+	},
+	"mono": {
+		Selected: "15",
+		Border:   "8",
+		Accent:   "7",
+		Playing:  "15",
+		Paused:   "7",
+		Stopped:  "8",
+	},
+}
+
 var selectedItemStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("205")).
+	Foreground(lipgloss.Color(themes[defaultTheme].Selected)).
 	Bold(true)
 
 var paneStyle = lipgloss.NewStyle().
 	Border(lipgloss.NormalBorder()).
-	BorderForeground(lipgloss.Color("80"))
+	BorderForeground(lipgloss.Color(themes[defaultTheme].Border))
+
+var errorStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("196")).
+	Bold(true)
+
+var accentStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color(themes[defaultTheme].Accent))
+
+var groupHeaderStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color(themes[defaultTheme].Accent)).
+	Bold(true).
+	Underline(true)
+
+var matchStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color(themes[defaultTheme].Accent)).
+	Underline(true)
+
+var statusStyles = map[CurrentStatus]lipgloss.Style{
+	Playing: lipgloss.NewStyle().Foreground(lipgloss.Color(themes[defaultTheme].Playing)).Bold(true),
+	Paused:  lipgloss.NewStyle().Foreground(lipgloss.Color(themes[defaultTheme].Paused)).Bold(true),
+	Stopped: lipgloss.NewStyle().Foreground(lipgloss.Color(themes[defaultTheme].Stopped)).Bold(true),
+}
+
+var replayGainModes = []string{player.ReplayGainOff, player.ReplayGainTrack, player.ReplayGainAlbum}
+
+type keybind struct {
+	keys        string
+	description string
+}
+
+type helpSection struct {
+	title    string
+	keybinds []keybind
+}
+
+var helpSections = []helpSection{
+	{
+		title: "Navigation",
+		keybinds: []keybind{
+			{"up / k", "Move cursor up"},
+			{"down / j", "Move cursor down"},
+			{"g / G", "Jump to top/bottom"},
+			{"ctrl+d / ctrl+u", "Half-page down/up"},
+			{"tab", "Switch view"},
+			{".", "Jump to now playing"},
+			{"/", "Fuzzy filter files by name"},
+			{"o", "Group files by album/artist"},
+			{"s", "Cycle file sort order"},
+		},
+	},
+	{
+		title: "Playback",
+		keybinds: []keybind{
+			{"enter", "Play selected item"},
+			{"space", "Play/pause"},
+			{"x", "Stop"},
+			{"left / right", "Seek backward/forward"},
+			{"- / +", "Volume down/up"},
+			{"m", "Toggle mute"},
+			{"r", "Cycle repeat mode"},
+			{"z", "Toggle shuffle"},
+			{"R", "Play random tracks from this view"},
+			{"b", "Replay the most recently played item"},
+			{"v", "Cycle ReplayGain mode"},
+			{"[ / ]", "Set A-B loop start/end"},
+			{"\\", "Clear A-B loop"},
+			{"e", "Cycle audio filter preset"},
+			{"D", "Choose audio device"},
+		},
+	},
+	{
+		title: "Queue",
+		keybinds: []keybind{
+			{"a", "Add selected song to queue"},
+			{"d", "Remove selected queue entry"},
+			{"shift+up / down", "Move selected station or queue entry"},
+		},
+	},
+	{
+		title: "Favorites",
+		keybinds: []keybind{
+			{"f", "Toggle favorite track or station"},
+			{"F", "Show only favorite stations"},
+		},
+	},
+	{
+		title: "Stations",
+		keybinds: []keybind{
+			{"n", "Add a radio station"},
+			{"w", "Open the station homepage"},
+		},
+	},
+	{
+		title: "General",
+		keybinds: []keybind{
+			{"?", "Toggle this help"},
+			{"q / ctrl+c", "Quit"},
+		},
+	},
+}
+
+var colorNames = map[string]string{
+	"black":          "0",
+	"red":            "1",
+	"green":          "2",
+	"yellow":         "3",
+	"blue":           "4",
+	"magenta":        "5",
+	"cyan":           "6",
+	"white":          "7",
+	"bright-black":   "8",
+	"gray":           "8",
+	"grey":           "8",
+	"bright-red":     "9",
+	"bright-green":   "10",
+	"bright-yellow":  "11",
+	"bright-blue":    "12",
+	"bright-magenta": "13",
+	"bright-cyan":    "14",
+	"bright-white":   "15",
+}
 
 const MaxLogHistory = 5
-const footerHeight = 10
+const minContentHeight = 7
+const paneBorderHeight = 2
+const paneBorderWidth = 2
+const minWidth = 40
+const progressBarRow = 2
+const errorDisplayDuration = 3 * time.Second
+const inputDebounce = 100 * time.Millisecond
+const volumeStep = 5
+const seekStep = 5
+const probeDelay = 300 * time.Millisecond
+const minHistoryPosition = 5.0
+const statusPollInterval = time.Second
+const alarmCheckInterval = 30 * time.Second
+const alarmGrace = 10 * time.Minute
 
 type Model struct {
-	width       int
-	height      int
-	songs       []string
-	cursor      int
-	player      *player.Player
-	musicDirs   []string
-	stations    []config.Stations
-	cmdChan     <-chan string
-	mprisServer *mpris.MprisServer
-	isPlaying   CurrentStatus
-	currentView CurrentView
-	logs        []string
-	logChan     <-chan string
+	width         int
+	height        int
+	songs         []scanner.MusicFile
+	scanning      bool
+	scanErr       error
+	visible       []int
+	matches       [][]int
+	lowerNames    []string
+	filterInput   bool
+	filterQuery   string
+	cursor        int
+	offset        int
+	player        *player.Player
+	musicDirs     []scanner.Directory
+	stations      []config.Stations
+	config        *config.Config
+	favoritesOnly bool
+	cmdChan       <-chan string
+	mprisServer   *mpris.MprisServer
+	notifier      *notify.Notifier
+	isPlaying     CurrentStatus
+	currentView   CurrentView
+	logs          []string
+	logChan       <-chan string
+	playerState   player.State
+	errorMessage  string
+	errorID       int
+	initialTracks []string
+	confirmQuit   bool
+	quitPrompt    bool
+	loopMode      player.LoopMode
+	shuffle       bool
+	showHelp      bool
+	pendingVolume float64
+	pendingSeek   float64
+	flushPending  bool
+	replayGain    string
+	resumeStore   *resume.Store
+	loopA         float64
+	loopB         float64
+	probed        map[string]bool
+	grouping      scanner.GroupMode
+	sortMode      scanner.SortMode
+	randomPlay    bool
+	randomView    CurrentView
+	randomLast    int
+	showDevices   bool
+	stationForm   bool
+	formFields    [2]string
+	formField     int
+	devices       []player.AudioDevice
+	deviceCursor  int
+	audioFilter   string
+	savedSession  *session.Session
+	restorePrompt bool
+	history       *history.History
+	historyTrack  string
+	historyAdded  bool
+	favorites     *favorites.Store
+	watcher       *watcher.Watcher
+	alarms        []alarm.Alarm
+	nextAlarm     alarm.Alarm
+	nextAlarmAt   time.Time
+	footerHeight  int
 }
 
 type CurrentStatus uint8
@@ -49,15 +274,68 @@ const (
 	Paused
 )
 
+var statusNames = map[CurrentStatus]string{
+	Stopped: "Stopped",
+	Playing: "Playing",
+	Paused:  "Paused",
+}
+
+var playerStatuses = map[player.Status]CurrentStatus{
+	player.StatusStopped: Stopped,
+	player.StatusPlaying: Playing,
+	player.StatusPaused:  Paused,
+}
+
 type CurrentView uint8
 
 const (
 	Files CurrentView = iota
 	Radios
+	Queue
+	History
+	Favorites
 )
 
+var viewNames = map[CurrentView]string{
+	Files:     "Files",
+	Radios:    "Stations",
+	Queue:     "Queue",
+	History:   "History",
+	Favorites: "Favorites",
+}
+
 type MprisCommand string
 type LogMessage string
+type StateMessage player.State
+type PlayerEventMessage player.Event
+type ClearErrorMessage int
+type FlushInputMessage struct{}
+type ProbeRequestMessage string
+type LibraryChangedMessage struct{}
+type AlarmTickMessage time.Time
+
+type StatusMessage struct {
+	status player.Status
+	err    error
+}
+
+type LibraryScannedMessage struct {
+	songs []scanner.MusicFile
+	err   error
+}
+
+type StationResolvedMessage struct {
+	name     string
+	location string
+	err      error
+}
+
+type ProbeResultMessage struct {
+	path     string
+	duration float64
+	codec    string
+	err      error
+}
 
 func NewModel(
 	player *player.Player,
@@ -65,43 +343,245 @@ func NewModel(
 	cmdChan <-chan string,
 	logChan <-chan string,
 	mprisServer *mpris.MprisServer,
+	notifier *notify.Notifier,
+	initialTracks []string,
 ) (*Model, error) {
-	if len(config.MusicDirs) == 0 {
-		return nil, fmt.Errorf("no music dirs provied")
+	applyTheme(config.Theme)
+
+	var resumeStore *resume.Store
+	if config.Resume {
+		var err error
+		if resumeStore, err = resume.LoadStore(); err != nil {
+			log.Printf("Failed to load saved positions: %v", err)
+		}
+	}
+
+	playHistory, err := history.Load(config.HistorySize, config.History)
+	if err != nil {
+		log.Printf("Failed to load history: %v", err)
+	}
+
+	var libraryWatcher *watcher.Watcher
+	if config.WatchDirs && len(initialTracks) == 0 {
+		var err error
+		if libraryWatcher, err = watcher.NewWatcher(config.ScanDirectories(), config.ScanOptions()); err != nil {
+			log.Printf("Failed to watch music directories: %v", err)
+		}
 	}
 
-	var songs []string
+	favoriteStore, err := favorites.LoadStore()
+	if err != nil {
+		log.Printf("Failed to load favorites: %v", err)
+	}
 
-	for _, dir := range config.MusicDirs {
-		files, err := os.ReadDir(dir)
+	var alarms []alarm.Alarm
+	for _, entry := range config.Alarms {
+		scheduled, err := alarm.New(entry.Time, entry.Days, entry.Source, entry.Volume)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read directory: %s", err)
+			log.Printf("Ignoring alarm: %v", err)
+			continue
 		}
+		alarms = append(alarms, scheduled)
+	}
 
-		for _, file := range files {
-			if file.IsDir() {
-				continue
-			}
-			path := filepath.Join(dir, file.Name())
-			songs = append(songs, path)
+	var savedSession *session.Session
+	if config.RestoreSession && len(initialTracks) == 0 {
+		var err error
+		if savedSession, err = session.Load(); err != nil {
+			log.Printf("Failed to load last session: %v", err)
 		}
 	}
 
 	return &Model{
-		songs:       songs,
-		player:      player,
-		musicDirs:   config.MusicDirs,
-		stations:    config.Stations,
-		cmdChan:     cmdChan,
-		logChan:     logChan,
-		mprisServer: mprisServer,
-		isPlaying:   Stopped,
-		currentView: Files,
+		scanning:      len(initialTracks) == 0,
+		player:        player,
+		musicDirs:     config.ScanDirectories(),
+		stations:      config.Stations,
+		config:        config,
+		cmdChan:       cmdChan,
+		logChan:       logChan,
+		mprisServer:   mprisServer,
+		notifier:      notifier,
+		isPlaying:     Stopped,
+		currentView:   Files,
+		initialTracks: initialTracks,
+		confirmQuit:   config.ConfirmQuit,
+		replayGain:    replayGainMode(config.ReplayGain),
+		audioFilter:   config.AudioFilter,
+		resumeStore:   resumeStore,
+		loopA:         -1,
+		loopB:         -1,
+		randomLast:    -1,
+		probed:        make(map[string]bool),
+		savedSession:  savedSession,
+		restorePrompt: savedSession != nil && len(savedSession.Queue) > 0,
+		history:       playHistory,
+		favorites:     favoriteStore,
+		watcher:       libraryWatcher,
+		alarms:        alarms,
 	}, nil
 }
 
 func (model *Model) Init() tea.Cmd {
-	return tea.Batch(waitForMprisCommand(model.cmdChan), waitForLogMessage(model.logChan), tea.SetWindowTitle("tunecli"))
+	var scan tea.Cmd
+	if model.scanning {
+		scan = model.scanLibrary(true)
+	}
+
+	return tea.Batch(
+		model.playTracks(model.initialTracks),
+		waitForMprisCommand(model.cmdChan),
+		waitForLogMessage(model.logChan),
+		waitForStateChange(model.player.StateChanges),
+		waitForPlayerEvent(model.player.Events),
+		scan,
+		model.pollStatus(),
+		model.scheduleAlarm(time.Now()),
+		tea.SetWindowTitle("tunecli"),
+	)
+}
+
+func (model *Model) pollStatus() tea.Cmd {
+	return tea.Tick(statusPollInterval, func(time.Time) tea.Msg {
+		status, err := model.player.Status()
+		return StatusMessage{status: status, err: err}
+	})
+}
+
+func (model *Model) scheduleAlarm(now time.Time) tea.Cmd {
+	next, at, ok := alarm.Next(model.alarms, now)
+	if !ok {
+		return nil
+	}
+
+	model.nextAlarm, model.nextAlarmAt = next, at
+	log.Printf("Next alarm at %s", at.Format("Mon 15:04"))
+
+	return model.waitForAlarm()
+}
+
+func (model *Model) waitForAlarm() tea.Cmd {
+	return tea.Tick(alarmCheckInterval, func(now time.Time) tea.Msg {
+		return AlarmTickMessage(now)
+	})
+}
+
+func (model *Model) fireAlarm(entry alarm.Alarm) tea.Cmd {
+	log.Printf("Alarm: playing %s", entry.Source)
+
+	model.randomPlay = false
+	model.rememberPosition()
+	model.clearABLoop()
+
+	if entry.Volume > 0 {
+		if err := model.player.SetVolume(entry.Volume); err != nil {
+			log.Printf("Failed to set alarm volume: %v", err)
+		}
+	}
+
+	name, location := filepath.Base(entry.Source), entry.Source
+	for _, station := range model.stations {
+		if station.Name == entry.Source {
+			name, location = station.Name, station.Url
+			break
+		}
+	}
+
+	resolved, ok := stream.Lookup(location)
+	if !ok {
+		return model.resolveStation(name, location)
+	}
+
+	return model.startPlayback(name, model.player.LoadFile(resolved))
+}
+
+func (model *Model) reconcileStatus(status player.Status) {
+	model.setStatus(playerStatuses[status])
+}
+
+func (model *Model) setStatus(status CurrentStatus) {
+	if status == model.isPlaying {
+		return
+	}
+
+	model.isPlaying = status
+	if err := model.mprisServer.SetPlaybackStatus(statusNames[status]); err != nil {
+		log.Printf("Failed to update MPRIS status: %v", err)
+	}
+}
+
+func (model *Model) handlePlayerEvent(event player.Event) tea.Cmd {
+	switch event.Type {
+	case player.EventFileLoaded:
+		if model.isPlaying == Stopped {
+			model.setStatus(Playing)
+		}
+	case player.EventPaused:
+		model.setStatus(Paused)
+	case player.EventResumed:
+		if model.isPlaying != Stopped {
+			model.setStatus(Playing)
+		}
+	case player.EventStopped:
+		model.setStatus(Stopped)
+	case player.EventError:
+		if event.Message == "" {
+			return model.showError("Playback error")
+		}
+		return model.showError("Playback error: " + event.Message)
+	}
+
+	return nil
+}
+
+func (model *Model) waitForLibraryChange() tea.Cmd {
+	if model.watcher == nil {
+		return nil
+	}
+
+	changes := model.watcher.Changes
+	return func() tea.Msg {
+		<-changes
+		return LibraryChangedMessage{}
+	}
+}
+
+func (model *Model) scanLibrary(probe bool) tea.Cmd {
+	dirs, options := model.musicDirs, model.config.ScanOptions()
+	options.ProbeDurations = options.ProbeDurations && probe
+
+	return func() tea.Msg {
+		songs, err := scanner.ScanDirectories(dirs, options)
+		return LibraryScannedMessage{songs: songs, err: err}
+	}
+}
+
+func (model *Model) updateLibrary(songs []scanner.MusicFile) {
+	selected, hasSelection := model.selectedSong()
+
+	known := make(map[string]scanner.MusicFile, len(model.songs))
+	for _, song := range model.songs {
+		known[song.Path] = song
+	}
+	for i, song := range songs {
+		if previous, ok := known[song.Path]; ok && previous.ModTime.Equal(song.ModTime) {
+			songs[i].Duration, songs[i].Codec = previous.Duration, previous.Codec
+		}
+	}
+
+	scanner.Sort(songs, model.grouping, model.sortMode)
+	model.songs = songs
+	model.indexSongs()
+
+	if hasSelection {
+		model.selectSong(selected.Path)
+	}
+	if model.currentView == Files {
+		model.cursor = max(min(model.cursor, len(model.visible)-1), 0)
+		model.scrollToCursor()
+	}
+
+	log.Printf("Library updated: %d songs", len(model.songs))
 }
 
 func waitForMprisCommand(cmdChan <-chan string) tea.Cmd {
@@ -116,72 +596,306 @@ func waitForLogMessage(logChan <-chan string) tea.Cmd {
 	}
 }
 
+func waitForStateChange(stateChan <-chan player.State) tea.Cmd {
+	return func() tea.Msg {
+		return StateMessage(<-stateChan)
+	}
+}
+
+func waitForPlayerEvent(events <-chan player.Event) tea.Cmd {
+	return func() tea.Msg {
+		return PlayerEventMessage(<-events)
+	}
+}
+
+func (model *Model) handleQuitPrompt(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "y", "Y", "q", "ctrl+c":
+		return tea.Quit
+	case "n", "N", "esc":
+		model.quitPrompt = false
+	}
+
+	return nil
+}
+
+func (model *Model) handleRestorePrompt(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c":
+		return tea.Quit
+	case "y", "Y":
+		model.restorePrompt = false
+		return model.restoreSession()
+	case "n", "N", "esc":
+		model.restorePrompt = false
+		model.savedSession = nil
+	}
+
+	return nil
+}
+
 func (model *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		var cmd tea.Cmd
+
+		if model.quitPrompt {
+			return model, model.handleQuitPrompt(msg)
+		}
+
+		if model.restorePrompt {
+			return model, model.handleRestorePrompt(msg)
+		}
+
+		if model.showHelp {
+			switch msg.String() {
+			case "ctrl+c":
+				return model, tea.Quit
+			case "?", "esc", "q":
+				model.showHelp = false
+			}
+
+			return model, nil
+		}
+
+		if model.showDevices {
+			return model, model.handleDevicePicker(msg)
+		}
+
+		if model.stationForm {
+			return model, model.handleStationForm(msg)
+		}
+
+		if model.filterInput {
+			return model, model.handleFilterInput(msg)
+		}
+
 		switch msg.String() {
 		case "ctrl+c":
 			return model, tea.Quit
 
+		case "q":
+			if !model.confirmQuit {
+				return model, tea.Quit
+			}
+
+			model.quitPrompt = true
+
+		case "?":
+			model.showHelp = true
+
 		case "tab":
 			switch model.currentView {
 			case Files:
 				model.currentView = Radios
 			case Radios:
+				model.currentView = Queue
+			case Queue:
+				model.currentView = History
+			case History:
+				model.currentView = Favorites
+			case Favorites:
 				model.currentView = Files
 			}
 
 			model.cursor = 0
 
+		case "+", "=":
+			model.pendingVolume += volumeStep
+			cmd = model.scheduleFlush()
+
+		case "-":
+			model.pendingVolume -= volumeStep
+			cmd = model.scheduleFlush()
+
+		case "right", "l":
+			model.pendingSeek += seekStep
+			cmd = model.scheduleFlush()
+
+		case "left", "h":
+			model.pendingSeek -= seekStep
+			cmd = model.scheduleFlush()
+
+		case "m":
+			if err := model.player.ToggleMute(); err != nil {
+				log.Printf("Failed to toggle mute: %v", err)
+			}
+
+		case ".":
+			model.jumpToNowPlaying()
+
+		case "r":
+			model.cycleLoopMode()
+
+		case "z":
+			model.toggleShuffle()
+
+		case "v":
+			model.cycleReplayGain()
+
+		case "[":
+			model.setLoopPoint(&model.loopA)
+
+		case "]":
+			model.setLoopPoint(&model.loopB)
+
+		case "\\":
+			model.clearABLoop()
+
+		case "o":
+			model.cycleGrouping()
+
+		case "s":
+			model.cycleSortMode()
+
+		case "D":
+			cmd = model.openDevicePicker()
+
+		case "e":
+			cmd = model.cycleAudioFilter()
+
+		case "f":
+			cmd = model.toggleFavorite()
+
+		case "F":
+			if model.currentView == Radios {
+				model.favoritesOnly = !model.favoritesOnly
+				model.cursor = 0
+			}
+
+		case "a":
+			cmd = model.enqueueSelected()
+
+		case "d":
+			model.removeSelectedFromQueue()
+
 		case "up", "k":
+			if model.listLength() == 0 {
+				break
+			}
+
 			model.cursor--
 
 			if model.cursor < 0 {
-				if model.currentView == Radios {
-					model.cursor = len(model.stations) - 1
-				} else {
-					model.cursor = len(model.songs) - 1
-				}
+				model.cursor = model.listLength() - 1
 			}
 
 		case "down", "j":
+			if model.listLength() == 0 {
+				break
+			}
+
 			model.cursor++
 
-			var boundary int
-			if model.currentView == Radios {
-				boundary = len(model.stations)
-			} else {
-				boundary = len(model.songs)
-			}
-			if model.cursor >= boundary {
+			if model.cursor >= model.listLength() {
 				model.cursor = 0
 			}
 
+		case "g", "home":
+			model.cursor = 0
+
+		case "G", "end":
+			model.cursor = max(model.listLength()-1, 0)
+
+		case "ctrl+d", "pgdown":
+			model.cursor = max(min(model.cursor+model.mainContentHeight()/2, model.listLength()-1), 0)
+
+		case "ctrl+u", "pgup":
+			model.cursor = max(model.cursor-model.mainContentHeight()/2, 0)
+
 		case "enter":
-			if model.currentView == Radios {
-				model.player.LoadFile(model.stations[model.cursor].Url)
-			} else {
-				model.player.LoadFile(model.songs[model.cursor])
-			}
+			model.randomPlay = false
+			cmd = model.playSelected()
 
-			if model.isPlaying == Paused {
-				model.player.TogglePause()
-			}
+		case "R":
+			cmd = model.playRandom()
 
-			model.mprisServer.SetPlaybackStatus("Playing")
-			model.isPlaying = Playing
+		case "b":
+			model.randomPlay = false
+			cmd = model.replayRecent()
 
 		case " ":
-			model.player.TogglePause()
+			if err := model.player.TogglePause(); err != nil {
+				log.Printf("Failed to toggle pause: %v", err)
+				break
+			}
 			switch model.isPlaying {
 			case Playing:
-				model.mprisServer.SetPlaybackStatus("Paused")
-				model.isPlaying = Paused
+				model.setStatus(Paused)
+				model.rememberPosition()
 			case Paused:
-				model.mprisServer.SetPlaybackStatus("Playing")
-				model.isPlaying = Playing
+				model.setStatus(Playing)
+			}
+
+		case "x":
+			model.stop()
+
+		case "n":
+			model.openStationForm()
+
+		case "w":
+			cmd = model.openHomepage()
+
+		case "/":
+			if model.currentView != Files {
+				model.currentView = Files
+				model.cursor = 0
+			}
+			model.filterInput = true
+
+		case "esc":
+			if model.filterQuery != "" {
+				model.setFilter("")
+			}
+
+		case "shift+up":
+			cmd = model.moveSelected(-1)
+
+		case "shift+down":
+			cmd = model.moveSelected(1)
+		}
+
+		model.scrollToCursor()
+
+		return model, tea.Batch(cmd, model.scheduleProbe())
+
+	case tea.MouseMsg:
+		if model.modalOpen() || model.tooSmall() {
+			return model, nil
+		}
+
+		return model, tea.Batch(model.handleMouse(msg), model.scheduleProbe())
+
+	case ProbeRequestMessage:
+		return model, model.probeSelected(string(msg))
+
+	case ProbeResultMessage:
+		if msg.err != nil {
+			log.Printf("Failed to probe %s: %v", filepath.Base(msg.path), msg.err)
+			return model, nil
+		}
+
+		for i := range model.songs {
+			if model.songs[i].Path == msg.path {
+				model.songs[i].Duration = msg.duration
+				model.songs[i].Codec = msg.codec
 			}
 		}
+
+		return model, nil
+
+	case FlushInputMessage:
+		model.flushPendingInput()
+
+		return model, nil
+
+	case ClearErrorMessage:
+		if int(msg) == model.errorID {
+			model.errorMessage = ""
+		}
+
+		return model, nil
+
 	case LogMessage:
 		model.logs = append(model.logs, string(msg))
 
@@ -191,95 +905,1896 @@ func (model *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return model, waitForLogMessage(model.logChan)
 
-	case MprisCommand:
-		if msg == "toggle_pause" && model.isPlaying != Stopped {
-			if err := model.player.TogglePause(); err != nil {
-				log.Printf("Failed to toggle pause: %v", err)
+	case StateMessage:
+		previous := model.playerState
+		model.playerState = player.State(msg)
+
+		if previous.Volume != model.playerState.Volume || previous.Muted != model.playerState.Muted {
+			model.syncMprisVolume()
+		}
+
+		if previous.Position != model.playerState.Position {
+			if err := model.mprisServer.UpdatePosition(model.playerState.Position); err != nil {
+				log.Printf("Failed to update MPRIS position: %v", err)
 			}
+		}
 
-			switch model.isPlaying {
-			case Playing:
-				model.mprisServer.SetPlaybackStatus("Paused")
-				model.isPlaying = Paused
-			case Paused:
-				model.mprisServer.SetPlaybackStatus("Playing")
-				model.isPlaying = Playing
+		if previous.Title != model.playerState.Title {
+			if err := model.mprisServer.SetMetadata(model.playerState.Title); err != nil {
+				log.Printf("Failed to update MPRIS metadata: %v", err)
 			}
+			model.notifyTrackChange()
 		}
 
-		return model, waitForMprisCommand(model.cmdChan)
+		model.recordHistory()
 
-	case tea.WindowSizeMsg:
-		model.width = msg.Width
-		model.height = msg.Height
+		var cmd tea.Cmd
+		if !previous.Idle && model.playerState.Idle {
+			if model.randomPlay {
+				model.currentView = model.randomView
+				cmd = model.playRandom()
+			}
+		}
 
-		return model, nil
-	}
+		model.cursor = max(min(model.cursor, model.listLength()-1), 0)
+		model.scrollToCursor()
 
-	return model, nil
-}
+		return model, tea.Batch(cmd, waitForStateChange(model.player.StateChanges))
 
-func (model *Model) View() string {
-	if model.width == 0 {
-		return "Initializing..."
-	}
-	mainContentHeight := model.height - footerHeight
+	case PlayerEventMessage:
+		cmd := model.handlePlayerEvent(player.Event(msg))
+		return model, tea.Batch(cmd, waitForPlayerEvent(model.player.Events))
 
-	leftPane := paneStyle.
-		Height(mainContentHeight).
-		Width(model.width/2 - 3).
-		Render(model.renderListPane())
+	case StatusMessage:
+		if msg.err == nil {
+			model.reconcileStatus(msg.status)
+		}
 
-	var status string
-	switch model.isPlaying {
-	case Playing:
-		status = "Playing"
-	case Paused:
-		status = "Paused"
-	case Stopped:
-		status = "Stopped"
-	}
+		return model, model.pollStatus()
 
-	rightPane := paneStyle.
-		Height(mainContentHeight).
-		Width(model.width / 2).
-		Render(status)
+	case LibraryChangedMessage:
+		return model, model.scanLibrary(false)
 
-	mainContent := lipgloss.JoinHorizontal(lipgloss.Top, leftPane, rightPane)
+	case LibraryScannedMessage:
+		model.scanning = false
+		model.scanErr = msg.err
 
-	keybinds := "Quit: <ctrl+c> | Switch View: tab | Play/Pause: space | Select song/station: enter"
-	logs := strings.Join(model.logs, "\n")
+		var cmd tea.Cmd
+		if msg.err != nil {
+			log.Printf("Failed to scan music directories: %v", msg.err)
+			cmd = model.showError("Failed to scan music directories")
+		} else {
+			model.updateLibrary(msg.songs)
+		}
 
-	footerContent := lipgloss.NewStyle().
-		PaddingTop(1).
-		Render(lipgloss.JoinVertical(lipgloss.Center, keybinds, "\n", logs))
+		return model, tea.Batch(cmd, model.waitForLibraryChange())
 
-	return lipgloss.JoinVertical(lipgloss.Center, mainContent, footerContent)
-}
+	case AlarmTickMessage:
+		now := time.Time(msg)
+		if now.Before(model.nextAlarmAt) {
+			return model, model.waitForAlarm()
+		}
 
-func (model *Model) renderListPane() string {
-	var builder strings.Builder
+		var cmd tea.Cmd
+		if now.Sub(model.nextAlarmAt) <= alarmGrace {
+			cmd = model.fireAlarm(model.nextAlarm)
+		} else {
+			log.Printf("Skipping alarm missed at %s", model.nextAlarmAt.Format("Mon 15:04"))
+		}
 
-	if model.currentView == Files {
-		for i, song := range model.songs {
-			song = filepath.Base(song)
-			if i == model.cursor {
-				builder.WriteString(selectedItemStyle.Render("> " + song))
-			} else {
-				builder.WriteString("  " + song)
+		return model, tea.Batch(cmd, model.scheduleAlarm(now))
+
+	case StationResolvedMessage:
+		if msg.err != nil {
+			log.Printf("Failed to resolve station %s: %v", msg.name, msg.err)
+			return model, model.showError("Failed to play " + msg.name)
+		}
+
+		return model, model.startPlayback(msg.name, model.player.LoadFile(msg.location))
+
+	case MprisCommand:
+		if (msg == "toggle_pause" || msg == "play") && model.isPlaying == Stopped {
+			return model, tea.Batch(model.playFromStopped(), waitForMprisCommand(model.cmdChan))
+		}
+
+		if msg == "toggle_pause" && model.isPlaying != Stopped ||
+			msg == "play" && model.isPlaying == Paused ||
+			msg == "pause" && model.isPlaying == Playing {
+			if err := model.player.TogglePause(); err != nil {
+				log.Printf("Failed to toggle pause: %v", err)
+			}
+
+			switch model.isPlaying {
+			case Playing:
+				model.setStatus(Paused)
+				model.rememberPosition()
+			case Paused:
+				model.setStatus(Playing)
 			}
-			builder.WriteString("\n")
 		}
-	} else {
-		for i, station := range model.stations {
-			if i == model.cursor {
-				builder.WriteString(selectedItemStyle.Render("> " + station.Name))
+
+		if msg == "stop" {
+			model.stop()
+		}
+
+		if volume, ok := mpris.ParseVolumeCommand(string(msg)); ok {
+			if err := model.player.SetVolume(volume); err != nil {
+				log.Printf("Failed to set volume: %v", err)
+			}
+		}
+
+		if seconds, absolute, ok := mpris.ParseSeekCommand(string(msg)); ok {
+			model.mprisSeek(seconds, absolute)
+		}
+
+		if mode, ok := mpris.ParseLoopCommand(string(msg)); ok {
+			model.setLoopMode(mode)
+		}
+
+		if shuffle, ok := mpris.ParseShuffleCommand(string(msg)); ok {
+			model.setShuffle(shuffle)
+		}
+
+		if location, ok := mpris.ParseOpenCommand(string(msg)); ok {
+			model.rememberPosition()
+			model.clearABLoop()
+			return model, tea.Batch(model.playTracks([]string{location}), waitForMprisCommand(model.cmdChan))
+		}
+
+		return model, waitForMprisCommand(model.cmdChan)
+
+	case tea.WindowSizeMsg:
+		model.width = msg.Width
+		model.height = msg.Height
+		model.footerHeight = lipgloss.Height(model.renderFooter())
+
+		model.cursor = max(min(model.cursor, model.listLength()-1), 0)
+		model.scrollToCursor()
+
+		return model, tea.ClearScreen
+	}
+
+	return model, nil
+}
+
+func (model *Model) View() string {
+	if model.width == 0 {
+		return "Initializing..."
+	}
+
+	footerContent := model.renderFooter()
+	if height := lipgloss.Height(footerContent); height != model.footerHeight {
+		model.footerHeight = height
+		model.scrollToCursor()
+	}
+
+	if model.tooSmall() {
+		return lipgloss.Place(
+			model.width,
+			model.height,
+			lipgloss.Center,
+			lipgloss.Center,
+			"Terminal too small",
+		)
+	}
+
+	if model.showHelp {
+		return lipgloss.Place(
+			model.width,
+			model.height,
+			lipgloss.Center,
+			lipgloss.Center,
+			model.renderHelp(),
+		)
+	}
+
+	if model.showDevices {
+		return lipgloss.Place(
+			model.width,
+			model.height,
+			lipgloss.Center,
+			lipgloss.Center,
+			model.renderDevicePicker(),
+		)
+	}
+
+	if model.stationForm {
+		return lipgloss.Place(
+			model.width,
+			model.height,
+			lipgloss.Center,
+			lipgloss.Center,
+			model.renderStationForm(),
+		)
+	}
+
+	mainContentHeight := model.mainContentHeight()
+
+	leftPane := paneStyle.
+		Height(mainContentHeight).
+		Width(model.leftPaneWidth()).
+		Render(model.renderListPane())
+
+	rightPane := paneStyle.
+		Height(mainContentHeight).
+		Width(model.rightPaneWidth()).
+		Render(model.renderStatusPane())
+
+	mainContent := lipgloss.JoinHorizontal(lipgloss.Top, leftPane, rightPane)
+
+	return lipgloss.JoinVertical(lipgloss.Center, mainContent, footerContent)
+}
+
+func (model *Model) renderHelp() string {
+	var sections []string
+
+	for _, section := range helpSections {
+		lines := []string{accentStyle.Bold(true).Render(section.title)}
+		for _, bind := range section.keybinds {
+			lines = append(lines, fmt.Sprintf("  %-16s %s", bind.keys, bind.description))
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	sections = append(sections, accentStyle.Bold(true).Render("Library")+"\n  "+model.renderLibraryStats())
+
+	return paneStyle.
+		Padding(0, 2).
+		Render(strings.Join(sections, "\n\n"))
+}
+
+func (model *Model) renderLibraryStats() string {
+	var size int64
+	var duration float64
+	unknown := 0
+
+	for _, song := range model.songs {
+		size += song.Size
+		if song.Duration > 0 {
+			duration += song.Duration
+		} else {
+			unknown++
+		}
+	}
+
+	stats := fmt.Sprintf("%d tracks · %s · %s", len(model.songs), formatSize(size), formatTotalDuration(duration))
+	if unknown > 0 && len(model.songs) > 0 {
+		stats += fmt.Sprintf(" (%d not probed)", unknown)
+	}
+
+	return stats
+}
+
+func (model *Model) renderDevicePicker() string {
+	current := model.config.AudioDevice
+	if current == "" {
+		current = "auto"
+	}
+
+	lines := []string{accentStyle.Bold(true).Render("Audio devices")}
+	for i, device := range model.devices {
+		line := device.Description
+		if line == "" {
+			line = device.Name
+		}
+		if device.Name == current {
+			line += " ♪"
+		}
+
+		if i == model.deviceCursor {
+			lines = append(lines, selectedItemStyle.Render("> "+line))
+		} else {
+			lines = append(lines, "  "+line)
+		}
+	}
+
+	return paneStyle.
+		Padding(0, 2).
+		Render(strings.Join(lines, "\n"))
+}
+
+func (model *Model) renderStationForm() string {
+	labels := []string{"Name", "URL "}
+
+	lines := []string{accentStyle.Bold(true).Render("Add station")}
+	for i, label := range labels {
+		if i == model.formField {
+			lines = append(lines, selectedItemStyle.Render(fmt.Sprintf("> %s: %s_", label, model.formFields[i])))
+		} else {
+			lines = append(lines, fmt.Sprintf("  %s: %s", label, model.formFields[i]))
+		}
+	}
+
+	if model.errorMessage != "" {
+		lines = append(lines, "", errorStyle.Render(model.errorMessage))
+	}
+	lines = append(lines, "", "enter: save · tab: next field · esc: cancel")
+
+	return paneStyle.
+		Padding(0, 2).
+		Render(strings.Join(lines, "\n"))
+}
+
+func (model *Model) renderFooter() string {
+	hints := "Help: ? | Quit: q | Switch View: tab | Play/Pause: space | Select: enter"
+	if station, ok := model.selectedStation(); ok && station.Homepage != "" {
+		hints += " | Homepage: w"
+	}
+
+	keybinds := lipgloss.JoinVertical(lipgloss.Center,
+		model.renderListPosition(),
+		accentStyle.Render(hints),
+	)
+
+	if model.quitPrompt {
+		keybinds = lipgloss.JoinVertical(lipgloss.Center, errorStyle.Render("Quit? (y/n)"), keybinds)
+	}
+
+	if model.restorePrompt {
+		keybinds = lipgloss.JoinVertical(lipgloss.Center, accentStyle.Render("Restore last session? (y/n)"), keybinds)
+	}
+
+	if model.errorMessage != "" {
+		keybinds = lipgloss.JoinVertical(lipgloss.Center, errorStyle.Render(model.errorMessage), keybinds)
+	}
+
+	content := keybinds
+	if len(model.logs) > 0 {
+		content = lipgloss.JoinVertical(lipgloss.Center, keybinds, "", strings.Join(model.logs, "\n"))
+	}
+
+	return lipgloss.NewStyle().
+		PaddingTop(1).
+		Width(model.width).
+		Align(lipgloss.Center).
+		Render(content)
+}
+
+func (model *Model) renderListPosition() string {
+	total := model.listLength()
+	current := 0
+	if total > 0 {
+		current = min(model.cursor, total-1) + 1
+	}
+
+	position := fmt.Sprintf("%s %d / %d", viewNames[model.currentView], current, total)
+	if model.currentView == Files {
+		position += " · sorted by " + sortModeNames[model.sortMode]
+	}
+	if model.filterInput {
+		position += " · /" + model.filterQuery + "_"
+	} else if model.filterQuery != "" && model.currentView == Files {
+		position += " · filter: " + model.filterQuery
+	}
+
+	return position
+}
+
+// listRow holds a song and its match positions instead of a rendered label,
+// so only the rows on screen pay for highlighting.
+type listRow struct {
+	text    string
+	index   int
+	song    *scanner.MusicFile
+	matches []int
+}
+
+func (model *Model) rowLabel(row listRow) string {
+	if row.song != nil {
+		return model.songLabel(*row.song, row.matches)
+	}
+	return row.text
+}
+
+func (model *Model) listRows() ([]listRow, string) {
+	var items []string
+
+	switch model.currentView {
+	case Files:
+		if model.scanning {
+			return nil, "Scanning..."
+		}
+		if len(model.songs) == 0 && model.scanErr != nil {
+			return nil, "Scan failed: " + model.scanErr.Error()
+		}
+		if len(model.songs) == 0 {
+			return nil, "No songs found"
+		}
+		if len(model.visible) == 0 {
+			return nil, "No songs match " + model.filterQuery
+		}
+
+		if model.grouping != scanner.GroupNone {
+			return model.groupedRows(), ""
+		}
+
+		rows := make([]listRow, len(model.visible))
+		for i, index := range model.visible {
+			rows[i] = listRow{index: i, song: &model.songs[index], matches: model.matches[i]}
+		}
+		return rows, ""
+	case Radios:
+		if len(model.stations) == 0 {
+			return nil, "No stations configured"
+		}
+
+		indices := model.stationIndices()
+		if len(indices) == 0 {
+			return nil, "No favorite stations"
+		}
+
+		for _, index := range indices {
+			station := model.stations[index]
+			if station.Favorite {
+				items = append(items, "★ "+station.Name)
 			} else {
-				builder.WriteString("  " + station.Name)
+				items = append(items, station.Name)
 			}
-			builder.WriteString("\n")
+		}
+	case Queue:
+		if len(model.playerState.Playlist) == 0 {
+			return nil, "Queue is empty"
+		}
+
+		for _, entry := range model.playerState.Playlist {
+			items = append(items, entry.Name())
+		}
+	case History:
+		if model.history.Len() == 0 {
+			return nil, "No history yet"
+		}
+
+		for _, entry := range model.history.Recent() {
+			items = append(items, entry.PlayedAt.Format("Jan 02 15:04")+"  "+historyName(entry))
+		}
+	case Favorites:
+		favoriteItems := model.favoriteItems()
+		if len(favoriteItems) == 0 {
+			return nil, "No favorites yet"
+		}
+
+		for _, item := range favoriteItems {
+			items = append(items, item.name)
 		}
 	}
 
-	return builder.String()
+	rows := make([]listRow, len(items))
+	for i, item := range items {
+		rows[i] = listRow{text: item, index: i}
+	}
+
+	return rows, ""
+}
+
+func (model *Model) groupedRows() []listRow {
+	var rows []listRow
+	var group string
+
+	for i, index := range model.visible {
+		song := &model.songs[index]
+		key := scanner.GroupKey(*song, model.grouping)
+		if i == 0 || key != group {
+			rows = append(rows, listRow{text: key, index: -1})
+			group = key
+		}
+		rows = append(rows, listRow{index: i, song: song, matches: model.matches[i]})
+	}
+
+	return rows
+}
+
+func (model *Model) cursorRow(rows []listRow) int {
+	for i, row := range rows {
+		if row.index == model.cursor {
+			return i
+		}
+	}
+
+	return 0
+}
+
+func (model *Model) renderListPane() string {
+	rows, placeholder := model.listRows()
+	if len(rows) == 0 {
+		return truncateLines(placeholder, model.leftPaneWidth())
+	}
+
+	var builder strings.Builder
+
+	end := min(model.offset+model.mainContentHeight(), len(rows))
+
+	for _, row := range rows[min(model.offset, end):end] {
+		label := model.rowLabel(row)
+
+		switch {
+		case row.index < 0:
+			builder.WriteString(groupHeaderStyle.Render(label))
+		case row.index == model.cursor:
+			builder.WriteString(selectedItemStyle.Render("> " + label))
+		case model.isActiveEntry(row.index):
+			builder.WriteString(accentStyle.Render("♪ " + label))
+		default:
+			builder.WriteString("  " + label)
+		}
+		builder.WriteString("\n")
+	}
+
+	return truncateLines(strings.TrimSuffix(builder.String(), "\n"), model.leftPaneWidth())
+}
+
+// truncateLines cuts every line to width cells so pane content never wraps
+// and each list row stays on exactly one terminal row.
+func truncateLines(text string, width int) string {
+	return lipgloss.NewStyle().MaxWidth(width).Render(text)
+}
+
+func (model *Model) renderStatusPane() string {
+	lines := make([]string, progressBarRow+2, progressBarRow+5)
+	lines[0] = statusStyles[model.isPlaying].Render(statusNames[model.isPlaying])
+	if model.playerState.Muted {
+		lines[0] += " " + errorStyle.Render("[muted]")
+	}
+	switch model.loopMode {
+	case player.LoopTrack:
+		lines[0] += " 🔂"
+	case player.LoopPlaylist:
+		lines[0] += " 🔁"
+	case player.LoopNone:
+	}
+	if model.shuffle {
+		lines[0] += " 🔀"
+	}
+	if model.randomPlay {
+		lines[0] += " 🎲"
+	}
+	if model.replayGain != player.ReplayGainOff {
+		lines[0] += " RG:" + model.replayGain
+	}
+	lines[1] = model.playerState.Title
+	lines[progressBarRow] = model.renderProgressBar(model.rightPaneWidth())
+	lines[progressBarRow+1] = timefmt.Progress(model.playerState.Position, model.playerState.Duration)
+
+	if song, ok := model.selectedSong(); ok {
+		lines = append(lines, "", accentStyle.Render(song.Name), model.renderSongInfo(song))
+	}
+
+	return truncateLines(strings.Join(lines, "\n"), model.rightPaneWidth())
+}
+
+func (model *Model) cycleLoopMode() {
+	model.setLoopMode((model.loopMode + 1) % player.LoopMode(len(mpris.LoopStatusNames)))
+}
+
+// setLoopMode always republishes the loop status, so a value an MPRIS client
+// wrote is replaced by the real mode when mpv rejects it.
+func (model *Model) setLoopMode(mode player.LoopMode) {
+	if err := model.player.SetLoop(mode); err != nil {
+		log.Printf("Failed to set loop mode: %v", err)
+	} else {
+		model.loopMode = mode
+	}
+
+	if err := model.mprisServer.SetLoopStatus(mpris.LoopStatusNames[model.loopMode]); err != nil {
+		log.Printf("Failed to update MPRIS loop status: %v", err)
+	}
+}
+
+func replayGainMode(mode string) string {
+	return player.ReplayGainMode(mode)
+}
+
+func (model *Model) cycleReplayGain() {
+	mode := replayGainModes[0]
+	for i, current := range replayGainModes {
+		if current == model.replayGain {
+			mode = replayGainModes[(i+1)%len(replayGainModes)]
+		}
+	}
+
+	if err := model.player.SetReplayGain(mode); err != nil {
+		log.Printf("Failed to set replaygain: %v", err)
+		return
+	}
+	model.replayGain = mode
+
+	log.Printf("ReplayGain: %s", mode)
+}
+
+var groupingNames = map[scanner.GroupMode]string{
+	scanner.GroupNone:   "off",
+	scanner.GroupAlbum:  "album",
+	scanner.GroupArtist: "artist",
+}
+
+var sortModeNames = map[scanner.SortMode]string{
+	scanner.SortPath:     "path",
+	scanner.SortName:     "name",
+	scanner.SortModified: "newest",
+	scanner.SortSize:     "size",
+}
+
+func (model *Model) cycleSortMode() {
+	model.sortMode = (model.sortMode + 1) % scanner.SortMode(len(sortModeNames))
+	model.resortSongs()
+}
+
+func (model *Model) resortSongs() {
+	selected, hasSelection := model.selectedSong()
+
+	scanner.Sort(model.songs, model.grouping, model.sortMode)
+	model.indexSongs()
+
+	if hasSelection {
+		model.selectSong(selected.Path)
+	}
+}
+
+func (model *Model) indexSongs() {
+	model.lowerNames = make([]string, len(model.songs))
+	for i, song := range model.songs {
+		model.lowerNames[i] = strings.ToLower(song.Name)
+	}
+
+	model.applyFilter()
+}
+
+func (model *Model) applyFilter() {
+	query := strings.ToLower(model.filterQuery)
+
+	type result struct {
+		index     int
+		score     int
+		positions []int
+	}
+
+	var results []result
+	for i, name := range model.lowerNames {
+		if score, positions, ok := fuzzy.Match(query, name); ok {
+			results = append(results, result{index: i, score: score, positions: positions})
+		}
+	}
+
+	if query != "" {
+		slices.SortStableFunc(results, func(a, b result) int {
+			keyA := scanner.GroupKey(model.songs[a.index], model.grouping)
+			keyB := scanner.GroupKey(model.songs[b.index], model.grouping)
+			if keyA != keyB {
+				return strings.Compare(keyA, keyB)
+			}
+			return b.score - a.score
+		})
+	}
+
+	model.visible = make([]int, len(results))
+	model.matches = make([][]int, len(results))
+	for i, result := range results {
+		model.visible[i], model.matches[i] = result.index, result.positions
+	}
+}
+
+func (model *Model) handleFilterInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return tea.Quit
+	case tea.KeyEsc:
+		model.filterInput = false
+		model.setFilter("")
+	case tea.KeyEnter:
+		model.filterInput = false
+	case tea.KeyUp:
+		model.cursor = max(model.cursor-1, 0)
+		model.scrollToCursor()
+	case tea.KeyDown:
+		model.cursor = max(min(model.cursor+1, len(model.visible)-1), 0)
+		model.scrollToCursor()
+	case tea.KeyBackspace:
+		if runes := []rune(model.filterQuery); len(runes) > 0 {
+			model.setFilter(string(runes[:len(runes)-1]))
+		}
+	case tea.KeySpace:
+		model.setFilter(model.filterQuery + " ")
+	case tea.KeyRunes:
+		model.setFilter(model.filterQuery + string(msg.Runes))
+	}
+
+	return model.scheduleProbe()
+}
+
+func (model *Model) setFilter(query string) {
+	model.filterQuery = query
+	model.applyFilter()
+	model.cursor = 0
+	model.offset = 0
+}
+
+func (model *Model) selectSong(path string) {
+	for i, index := range model.visible {
+		if model.songs[index].Path == path {
+			model.cursor = i
+			return
+		}
+	}
+}
+
+func (model *Model) cycleGrouping() {
+	model.grouping = (model.grouping + 1) % scanner.GroupMode(len(groupingNames))
+	model.resortSongs()
+
+	log.Printf("Grouping: %s", groupingNames[model.grouping])
+}
+
+func (model *Model) cycleAudioFilter() tea.Cmd {
+	names := append([]string{""}, model.config.AudioFilterNames()...)
+
+	name := names[0]
+	for i, current := range names {
+		if current == model.audioFilter {
+			name = names[(i+1)%len(names)]
+		}
+	}
+
+	filter, err := model.config.AudioFilterChain(name)
+	if err == nil {
+		err = model.player.SetAudioFilter(filter)
+	}
+	if err != nil {
+		log.Printf("Failed to set audio filter: %v", err)
+		return model.showError("Failed to set audio filter")
+	}
+	model.audioFilter = name
+
+	if name == "" {
+		name = "off"
+	}
+	log.Printf("Audio filter: %s", name)
+
+	return nil
+}
+
+func (model *Model) toggleShuffle() {
+	model.setShuffle(!model.shuffle)
+}
+
+func (model *Model) setShuffle(enabled bool) {
+	if enabled != model.shuffle {
+		if err := model.player.SetShuffle(enabled); err != nil {
+			log.Printf("Failed to set shuffle: %v", err)
+		} else {
+			model.shuffle = enabled
+		}
+	}
+
+	if err := model.mprisServer.SetShuffle(model.shuffle); err != nil {
+		log.Printf("Failed to update MPRIS shuffle: %v", err)
+	}
+}
+
+func (model *Model) syncMprisVolume() {
+	volume := model.playerState.Volume / 100
+	if model.playerState.Muted {
+		volume = 0
+	}
+
+	if err := model.mprisServer.SetVolume(volume); err != nil {
+		log.Printf("Failed to update MPRIS volume: %v", err)
+	}
+}
+
+func (model *Model) renderProgressBar(width int) string {
+	duration := model.playerState.Duration
+	if duration <= 0 {
+		return strings.Repeat("─", width)
+	}
+
+	var builder strings.Builder
+
+	for i := range width {
+		at := float64(i) / float64(width) * duration
+
+		char := "─"
+		if at < model.playerState.Position {
+			char = "━"
+		}
+
+		switch {
+		case model.loopA >= 0 && at >= model.loopA && (model.loopB < 0 || at <= model.loopB):
+			builder.WriteString(selectedItemStyle.Render(char))
+		case at < model.playerState.Position:
+			builder.WriteString(accentStyle.Render(char))
+		default:
+			builder.WriteString(char)
+		}
+	}
+
+	return builder.String()
+}
+
+func (model *Model) setLoopPoint(point *float64) {
+	if model.isPlaying == Stopped {
+		return
+	}
+
+	*point = model.playerState.Position
+	if model.loopA >= 0 && model.loopB >= 0 && model.loopB < model.loopA {
+		model.loopA, model.loopB = model.loopB, model.loopA
+	}
+
+	if err := model.player.SetABLoop(model.loopA, model.loopB); err != nil {
+		log.Printf("Failed to set A-B loop: %v", err)
+	}
+}
+
+func (model *Model) clearABLoop() {
+	if model.loopA < 0 && model.loopB < 0 {
+		return
+	}
+
+	model.loopA, model.loopB = -1, -1
+	if err := model.player.ClearABLoop(); err != nil {
+		log.Printf("Failed to clear A-B loop: %v", err)
+	}
+}
+
+func (model *Model) renderSongInfo(song scanner.MusicFile) string {
+	info := []string{song.Format, formatSize(song.Size)}
+
+	if song.Duration > 0 {
+		info = append(info, timefmt.Duration(song.Duration))
+	} else {
+		info = append(info, timefmt.Unknown)
+	}
+	if song.Codec != "" {
+		info = append(info, song.Codec)
+	}
+
+	return strings.Join(info, " · ")
+}
+
+func formatTotalDuration(seconds float64) string {
+	total := int(max(seconds, 0))
+	return fmt.Sprintf("%dh %02dm", total/3600, total%3600/60)
+}
+
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	value := float64(bytes)
+	suffixes := []string{"KB", "MB", "GB", "TB"}
+	for _, suffix := range suffixes {
+		value /= unit
+		if math.Round(value*10)/10 < unit || suffix == suffixes[len(suffixes)-1] {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+
+	return ""
+}
+
+func (model *Model) playSelected() tea.Cmd {
+	if model.cursor < 0 || model.cursor >= model.listLength() {
+		return nil
+	}
+
+	var err error
+	var name string
+
+	model.rememberPosition()
+	model.clearABLoop()
+
+	switch model.currentView {
+	case Files:
+		song, _ := model.selectedSong()
+		name = song.Name
+		err = model.player.LoadFileAt(song.Path, model.savedPosition(song.Path))
+	case Radios:
+		station := model.stations[model.stationIndices()[model.cursor]]
+		location, ok := stream.Lookup(station.Url)
+		if !ok {
+			return model.resolveStation(station.Name, station.Url)
+		}
+		name = station.Name
+		err = model.player.LoadFile(location)
+	case Queue:
+		name = model.playerState.Playlist[model.cursor].Name()
+		err = model.player.PlayIndex(model.cursor)
+	case History:
+		entry := model.history.Recent()[model.cursor]
+		name = historyName(entry)
+		err = model.player.LoadFile(entry.Path)
+	case Favorites:
+		item := model.favoriteItems()[model.cursor]
+		location, ok := stream.Lookup(item.location)
+		if !ok {
+			return model.resolveStation(item.name, item.location)
+		}
+		start := 0.0
+		if item.station < 0 {
+			start = model.savedPosition(item.location)
+		}
+		name = item.name
+		err = model.player.LoadFileAt(location, start)
+	}
+
+	return model.startPlayback(name, err)
+}
+
+func (model *Model) replayRecent() tea.Cmd {
+	if model.history.Len() == 0 {
+		return model.showError("Nothing played yet")
+	}
+
+	current := model.nowPlaying()
+	recent := model.history.Recent()
+	index := slices.IndexFunc(recent, func(entry history.Entry) bool {
+		return entry.Path != current
+	})
+	if index < 0 {
+		return model.showError("No earlier track to replay")
+	}
+
+	entry := recent[index]
+
+	model.rememberPosition()
+	model.clearABLoop()
+
+	return model.startPlayback(historyName(entry), model.player.LoadFile(entry.Path))
+}
+
+func (model *Model) resolveStation(name string, location string) tea.Cmd {
+	return func() tea.Msg {
+		resolved, err := stream.Resolve(location)
+		return StationResolvedMessage{name: name, location: resolved, err: err}
+	}
+}
+
+func (model *Model) startPlayback(name string, err error) tea.Cmd {
+	if err != nil {
+		log.Printf("Failed to load file: %v", err)
+		model.setStatus(Stopped)
+
+		return model.showError("Failed to play " + name)
+	}
+
+	if model.isPlaying == Paused {
+		if err := model.player.TogglePause(); err != nil {
+			log.Printf("Failed to resume playback: %v", err)
+		}
+	}
+
+	model.setStatus(Playing)
+
+	return nil
+}
+
+func (model *Model) playRandom() tea.Cmd {
+	length := model.listLength()
+	if length == 0 {
+		model.randomPlay = false
+		return nil
+	}
+
+	index := rand.IntN(length)
+	if length > 1 && model.randomView == model.currentView && index == model.randomLast {
+		index = (index + 1 + rand.IntN(length-1)) % length
+	}
+
+	model.randomPlay = true
+	model.randomView = model.currentView
+	model.randomLast = index
+	model.cursor = index
+	model.scrollToCursor()
+
+	return model.playSelected()
+}
+
+// playFromStopped restarts the current playlist entry when mpv still holds a
+// playlist, and otherwise plays the item under the cursor.
+func (model *Model) playFromStopped() tea.Cmd {
+	playlist := model.playerState.Playlist
+	if len(playlist) == 0 {
+		return model.playSelected()
+	}
+
+	index := max(slices.IndexFunc(playlist, func(entry player.PlaylistEntry) bool { return entry.Current }), 0)
+	model.clearABLoop()
+	return model.startPlayback(playlist[index].Name(), model.player.PlayIndex(index))
+}
+
+func (model *Model) playTracks(tracks []string) tea.Cmd {
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	if err := model.player.LoadFile(tracks[0]); err != nil {
+		log.Printf("Failed to load file: %v", err)
+		return model.showError("Failed to play " + filepath.Base(tracks[0]))
+	}
+
+	for _, track := range tracks[1:] {
+		if err := model.player.AppendFile(track); err != nil {
+			log.Printf("Failed to enqueue file: %v", err)
+		}
+	}
+
+	if model.isPlaying == Paused {
+		if err := model.player.TogglePause(); err != nil {
+			log.Printf("Failed to resume playback: %v", err)
+		}
+	}
+
+	model.setStatus(Playing)
+
+	return nil
+}
+
+func (model *Model) enqueueSelected() tea.Cmd {
+	song, ok := model.selectedSong()
+	if !ok {
+		return nil
+	}
+
+	if err := model.player.AppendFile(song.Path); err != nil {
+		log.Printf("Failed to enqueue file: %v", err)
+		return model.showError("Failed to enqueue " + song.Name)
+	}
+
+	if model.isPlaying == Stopped {
+		model.setStatus(Playing)
+	}
+
+	return nil
+}
+
+func (model *Model) removeSelectedFromQueue() {
+	if model.currentView != Queue || model.cursor >= model.listLength() {
+		return
+	}
+
+	if err := model.player.RemoveFromPlaylist(model.cursor); err != nil {
+		log.Printf("Failed to remove from queue: %v", err)
+	}
+}
+
+func (model *Model) moveSelected(delta int) tea.Cmd {
+	target := model.cursor + delta
+	if model.cursor >= model.listLength() || target < 0 || target >= model.listLength() {
+		return nil
+	}
+
+	switch model.currentView {
+	case Radios:
+		indices := model.stationIndices()
+		from, to := indices[model.cursor], indices[target]
+		model.stations[from], model.stations[to] = model.stations[to], model.stations[from]
+
+		model.config.Stations = model.stations
+		if err := model.config.Save(model.config.Path); err != nil {
+			model.stations[from], model.stations[to] = model.stations[to], model.stations[from]
+			log.Printf("Failed to save config: %v", err)
+			return model.showError("Failed to save station order")
+		}
+	case Queue:
+		if err := model.player.MovePlaylistEntry(model.cursor, target); err != nil {
+			log.Printf("Failed to move queue entry: %v", err)
+			return model.showError("Failed to move queue entry")
+		}
+		playlist := slices.Clone(model.playerState.Playlist)
+		playlist[model.cursor], playlist[target] = playlist[target], playlist[model.cursor]
+		model.playerState.Playlist = playlist
+	default:
+		return nil
+	}
+
+	model.cursor = target
+	return nil
+}
+
+func (model *Model) nowPlaying() string {
+	for _, entry := range model.playerState.Playlist {
+		if entry.Current {
+			return entry.Filename
+		}
+	}
+	return ""
+}
+
+func (model *Model) notifyTrackChange() {
+	location := model.nowPlaying()
+
+	var artist string
+	for _, song := range model.songs {
+		if song.Path == location {
+			artist = song.Artist
+			break
+		}
+	}
+
+	model.notifier.TrackChanged(model.playerState.Title, artist, location)
+}
+
+func (model *Model) recordHistory() {
+	path := model.nowPlaying()
+	if path != model.historyTrack {
+		model.historyTrack = path
+		model.historyAdded = false
+	}
+
+	if model.historyAdded || path == "" || model.playerState.Position < minHistoryPosition {
+		return
+	}
+	model.historyAdded = true
+
+	model.history.Add(history.Entry{
+		Path:     path,
+		Title:    model.playerState.Title,
+		PlayedAt: time.Now(),
+	})
+	if err := model.history.Save(); err != nil {
+		log.Printf("Failed to save history: %v", err)
+	}
+}
+
+func historyName(entry history.Entry) string {
+	if entry.Title != "" {
+		return entry.Title
+	}
+	return filepath.Base(entry.Path)
+}
+
+func (model *Model) jumpToNowPlaying() {
+	if index, ok := model.nowPlayingIndex(model.currentView); ok {
+		model.cursor = index
+		return
+	}
+
+	for _, view := range []CurrentView{Files, Radios, Queue} {
+		if index, ok := model.nowPlayingIndex(view); ok {
+			model.currentView = view
+			model.cursor = index
+			return
+		}
+	}
+}
+
+func (model *Model) nowPlayingIndex(view CurrentView) (int, bool) {
+	path := model.nowPlaying()
+	if path == "" {
+		return 0, false
+	}
+
+	switch view {
+	case Files:
+		for i, index := range model.visible {
+			if model.songs[index].Path == path {
+				return i, true
+			}
+		}
+	case Radios:
+		for i, index := range model.stationIndices() {
+			if location, _ := stream.Lookup(model.stations[index].Url); location == path {
+				return i, true
+			}
+		}
+	case Queue:
+		for i, entry := range model.playerState.Playlist {
+			if entry.Current {
+				return i, true
+			}
+		}
+	case Favorites:
+		for i, item := range model.favoriteItems() {
+			if location, _ := stream.Lookup(item.location); location == path {
+				return i, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func (model *Model) stationIndices() []int {
+	indices := make([]int, 0, len(model.stations))
+	for i, station := range model.stations {
+		if !model.favoritesOnly || station.Favorite {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+func (model *Model) openDevicePicker() tea.Cmd {
+	devices, err := model.player.ListAudioDevices()
+	if err != nil {
+		log.Printf("Failed to list audio devices: %v", err)
+		return model.showError("Failed to list audio devices")
+	}
+	if len(devices) == 0 {
+		return model.showError("No audio devices found")
+	}
+
+	model.devices = devices
+	model.deviceCursor = 0
+	for i, device := range devices {
+		if device.Name == model.config.AudioDevice {
+			model.deviceCursor = i
+		}
+	}
+	model.showDevices = true
+
+	return nil
+}
+
+func (model *Model) handleDevicePicker(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c":
+		return tea.Quit
+	case "esc", "q", "D":
+		model.showDevices = false
+	case "up", "k":
+		model.deviceCursor = (model.deviceCursor - 1 + len(model.devices)) % len(model.devices)
+	case "down", "j":
+		model.deviceCursor = (model.deviceCursor + 1) % len(model.devices)
+	case "enter":
+		model.showDevices = false
+		return model.selectAudioDevice(model.devices[model.deviceCursor])
+	}
+
+	return nil
+}
+
+func (model *Model) selectAudioDevice(device player.AudioDevice) tea.Cmd {
+	if err := model.player.SetAudioDevice(device.Name); err != nil {
+		log.Printf("Failed to set audio device: %v", err)
+		return model.showError("Failed to switch audio device")
+	}
+	log.Printf("Audio device: %s", device.Name)
+
+	model.config.AudioDevice = device.Name
+	if err := model.config.Save(model.config.Path); err != nil {
+		log.Printf("Failed to save config: %v", err)
+		return model.showError("Failed to save audio device")
+	}
+
+	return nil
+}
+
+func (model *Model) openStationForm() {
+	model.formFields = [2]string{}
+	model.formField = 0
+	model.stationForm = true
+}
+
+func (model *Model) handleStationForm(msg tea.KeyMsg) tea.Cmd {
+	field := &model.formFields[model.formField]
+
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return tea.Quit
+	case tea.KeyEsc:
+		model.stationForm = false
+	case tea.KeyTab, tea.KeyShiftTab, tea.KeyUp, tea.KeyDown:
+		model.formField = (model.formField + 1) % len(model.formFields)
+	case tea.KeyEnter:
+		if model.formField == 0 {
+			model.formField = 1
+			return nil
+		}
+		return model.addStation(model.formFields[0], model.formFields[1])
+	case tea.KeyBackspace:
+		if runes := []rune(*field); len(runes) > 0 {
+			*field = string(runes[:len(runes)-1])
+		}
+	case tea.KeySpace:
+		*field += " "
+	case tea.KeyRunes:
+		*field += string(msg.Runes)
+	}
+
+	return nil
+}
+
+func (model *Model) addStation(name string, location string) tea.Cmd {
+	name, location = strings.TrimSpace(name), strings.TrimSpace(location)
+
+	if name == "" {
+		return model.showError("Station name is required")
+	}
+	parsed, err := url.Parse(location)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return model.showError("Station URL must be an http(s) URL")
+	}
+	for _, station := range model.stations {
+		if strings.EqualFold(station.Name, name) || station.Url == location {
+			return model.showError("Station already exists: " + station.Name)
+		}
+	}
+
+	previous := model.stations
+	model.stations = append(slices.Clip(previous), config.Stations{Name: name, Url: location})
+	model.config.Stations = model.stations
+	if err := model.config.Save(model.config.Path); err != nil {
+		model.stations, model.config.Stations = previous, previous
+		log.Printf("Failed to save config: %v", err)
+		return model.showError("Failed to save station")
+	}
+	log.Printf("Added station %s", name)
+
+	model.stationForm = false
+	model.favoritesOnly = false
+	model.currentView = Radios
+	model.cursor = len(model.stations) - 1
+	model.scrollToCursor()
+
+	return nil
+}
+
+func (model *Model) selectedStation() (config.Stations, bool) {
+	if model.cursor < 0 || model.cursor >= model.listLength() {
+		return config.Stations{}, false
+	}
+
+	switch model.currentView {
+	case Radios:
+		return model.stations[model.stationIndices()[model.cursor]], true
+	case Favorites:
+		if item := model.favoriteItems()[model.cursor]; item.station >= 0 {
+			return model.stations[item.station], true
+		}
+	}
+
+	return config.Stations{}, false
+}
+
+func (model *Model) openHomepage() tea.Cmd {
+	station, ok := model.selectedStation()
+	if !ok || station.Homepage == "" {
+		return nil
+	}
+
+	if err := openURL(station.Homepage); err != nil {
+		log.Printf("Failed to open homepage: %v", err)
+		return model.showError("Failed to open " + station.Homepage)
+	}
+
+	return nil
+}
+
+func openURL(location string) error {
+	parsed, err := url.Parse(location)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("unsupported url: %s", location)
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", location)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", location)
+	default:
+		cmd = exec.Command("xdg-open", location)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start browser: %s", err)
+	}
+	go cmd.Wait()
+
+	return nil
+}
+
+type favoriteItem struct {
+	name     string
+	location string
+	station  int
+}
+
+func (model *Model) favoriteItems() []favoriteItem {
+	var items []favoriteItem
+
+	for i, station := range model.stations {
+		if station.Favorite {
+			items = append(items, favoriteItem{name: station.Name, location: station.Url, station: i})
+		}
+	}
+
+	if model.favorites != nil {
+		for _, path := range model.favorites.Items() {
+			items = append(items, favoriteItem{name: filepath.Base(path), location: path, station: -1})
+		}
+	}
+
+	return items
+}
+
+func (model *Model) isFavorite(path string) bool {
+	return model.favorites != nil && model.favorites.Contains(path)
+}
+
+func (model *Model) songLabel(song scanner.MusicFile, positions []int) string {
+	name := highlightMatch(song.Name, positions)
+	if model.isFavorite(song.Path) {
+		return "★ " + name
+	}
+	return name
+}
+
+func highlightMatch(name string, positions []int) string {
+	if len(positions) == 0 || len(strings.ToLower(name)) != len(name) {
+		return name
+	}
+
+	var builder strings.Builder
+	last := 0
+	for _, position := range positions {
+		_, size := utf8.DecodeRuneInString(name[position:])
+		builder.WriteString(name[last:position])
+		builder.WriteString(matchStyle.Render(name[position : position+size]))
+		last = position + size
+	}
+	builder.WriteString(name[last:])
+
+	return builder.String()
+}
+
+func (model *Model) toggleFavorite() tea.Cmd {
+	if model.cursor >= model.listLength() {
+		return nil
+	}
+
+	var cmd tea.Cmd
+
+	switch model.currentView {
+	case Radios:
+		cmd = model.toggleFavoriteStation(model.stationIndices()[model.cursor])
+	case Files:
+		song, _ := model.selectedSong()
+		cmd = model.toggleFavoriteTrack(song.Path)
+	case Favorites:
+		item := model.favoriteItems()[model.cursor]
+		if item.station >= 0 {
+			cmd = model.toggleFavoriteStation(item.station)
+		} else {
+			cmd = model.toggleFavoriteTrack(item.location)
+		}
+	}
+
+	model.cursor = max(min(model.cursor, model.listLength()-1), 0)
+
+	return cmd
+}
+
+func (model *Model) toggleFavoriteStation(index int) tea.Cmd {
+	station := &model.stations[index]
+	station.Favorite = !station.Favorite
+
+	model.config.Stations = model.stations
+	if err := model.config.Save(model.config.Path); err != nil {
+		log.Printf("Failed to save config: %v", err)
+		return model.showError("Failed to save favorites")
+	}
+
+	return nil
+}
+
+func (model *Model) toggleFavoriteTrack(path string) tea.Cmd {
+	if model.favorites == nil {
+		return model.showError("Favorites are unavailable")
+	}
+
+	model.favorites.Toggle(path)
+	if err := model.favorites.Save(); err != nil {
+		log.Printf("Failed to save favorites: %v", err)
+		return model.showError("Failed to save favorites")
+	}
+
+	return nil
+}
+
+func (model *Model) isActiveEntry(index int) bool {
+	return model.currentView == Queue && model.playerState.Playlist[index].Current
+}
+
+func (model *Model) selectedSong() (scanner.MusicFile, bool) {
+	if model.currentView != Files || model.cursor < 0 || model.cursor >= len(model.visible) {
+		return scanner.MusicFile{}, false
+	}
+	return model.songs[model.visible[model.cursor]], true
+}
+
+func (model *Model) scheduleProbe() tea.Cmd {
+	song, ok := model.selectedSong()
+	if !ok || song.Duration > 0 || model.probed[song.Path] {
+		return nil
+	}
+
+	return tea.Tick(probeDelay, func(time.Time) tea.Msg {
+		return ProbeRequestMessage(song.Path)
+	})
+}
+
+func (model *Model) probeSelected(path string) tea.Cmd {
+	song, ok := model.selectedSong()
+	if !ok || song.Path != path || model.probed[path] {
+		return nil
+	}
+	model.probed[path] = true
+
+	return func() tea.Msg {
+		duration, codec, err := scanner.Probe(path)
+		return ProbeResultMessage{path: path, duration: duration, codec: codec, err: err}
+	}
+}
+
+func (model *Model) scheduleFlush() tea.Cmd {
+	if model.flushPending {
+		return nil
+	}
+	model.flushPending = true
+
+	return tea.Tick(inputDebounce, func(time.Time) tea.Msg {
+		return FlushInputMessage{}
+	})
+}
+
+func (model *Model) flushPendingInput() {
+	model.flushPending = false
+
+	if model.pendingVolume != 0 {
+		if err := model.player.ChangeVolume(model.pendingVolume); err != nil {
+			log.Printf("Failed to change volume: %v", err)
+		}
+		model.pendingVolume = 0
+	}
+
+	if model.pendingSeek != 0 {
+		if err := model.player.SeekRelative(model.pendingSeek); err != nil {
+			log.Printf("Failed to seek: %v", err)
+		}
+		model.pendingSeek = 0
+	}
+}
+
+func (model *Model) stop() {
+	model.randomPlay = false
+	if model.isPlaying == Stopped {
+		return
+	}
+
+	model.rememberPosition()
+
+	if err := model.player.Stop(); err != nil {
+		log.Printf("Failed to stop: %v", err)
+		return
+	}
+
+	model.setStatus(Stopped)
+	if err := model.mprisServer.SetMetadata(""); err != nil {
+		log.Printf("Failed to update MPRIS metadata: %v", err)
+	}
+}
+
+func (model *Model) rememberPosition() {
+	path := model.nowPlaying()
+	if model.resumeStore == nil || path == "" {
+		return
+	}
+
+	model.resumeStore.Set(path, model.playerState.Position, model.playerState.Duration)
+	if err := model.resumeStore.Save(); err != nil {
+		log.Printf("Failed to save position: %v", err)
+	}
+}
+
+func (model *Model) savedPosition(path string) float64 {
+	if model.resumeStore == nil {
+		return 0
+	}
+
+	position, ok := model.resumeStore.Get(path)
+	if !ok {
+		return 0
+	}
+
+	log.Printf("Resuming at %s", timefmt.Duration(position))
+	return position
+}
+
+func (model *Model) SaveState() {
+	model.rememberPosition()
+	model.saveSession()
+}
+
+func (model *Model) Close() {
+	if model.watcher == nil {
+		return
+	}
+
+	if err := model.watcher.Close(); err != nil {
+		log.Printf("Failed to close library watcher: %v", err)
+	}
+}
+
+func (model *Model) saveSession() {
+	if !model.config.RestoreSession {
+		return
+	}
+
+	saved := session.Session{
+		Volume:  model.playerState.Volume,
+		Loop:    model.loopMode,
+		Shuffle: model.shuffle,
+	}
+	for i, entry := range model.playerState.Playlist {
+		saved.Queue = append(saved.Queue, entry.Filename)
+		if entry.Current {
+			saved.Index = i
+			saved.Position = model.playerState.Position
+		}
+	}
+
+	if len(saved.Queue) == 0 {
+		if err := session.Clear(); err != nil {
+			log.Printf("Failed to clear session: %v", err)
+		}
+		return
+	}
+
+	if err := saved.Save(); err != nil {
+		log.Printf("Failed to save session: %v", err)
+	}
+}
+
+func (model *Model) restoreSession() tea.Cmd {
+	saved := model.savedSession
+	model.savedSession = nil
+	if saved == nil {
+		return nil
+	}
+
+	var queue []string
+	index, position := 0, 0.0
+	for i, path := range saved.Queue {
+		if !strings.Contains(path, "://") {
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+		}
+		if i == saved.Index {
+			index, position = len(queue), saved.Position
+		}
+		queue = append(queue, path)
+	}
+
+	if len(queue) == 0 {
+		return model.showError("Nothing to restore")
+	}
+
+	if position > 0 {
+		model.player.SeekOnLoad(position)
+	}
+	if err := model.player.LoadPlaylist(queue, index); err != nil {
+		log.Printf("Failed to restore session: %v", err)
+		return model.showError("Failed to restore session")
+	}
+
+	if saved.Volume > 0 {
+		if err := model.player.SetVolume(saved.Volume); err != nil {
+			log.Printf("Failed to restore volume: %v", err)
+		}
+	}
+
+	if _, ok := mpris.LoopStatusNames[saved.Loop]; ok && saved.Loop != model.loopMode {
+		if err := model.player.SetLoop(saved.Loop); err != nil {
+			log.Printf("Failed to restore loop mode: %v", err)
+		} else {
+			model.loopMode = saved.Loop
+		}
+		if err := model.mprisServer.SetLoopStatus(mpris.LoopStatusNames[model.loopMode]); err != nil {
+			log.Printf("Failed to update MPRIS loop status: %v", err)
+		}
+	}
+
+	model.shuffle = saved.Shuffle
+	if err := model.mprisServer.SetShuffle(model.shuffle); err != nil {
+		log.Printf("Failed to update MPRIS shuffle: %v", err)
+	}
+
+	model.setStatus(Playing)
+	log.Printf("Restored session with %d tracks", len(queue))
+
+	return nil
+}
+
+func (model *Model) showError(message string) tea.Cmd {
+	model.errorID++
+	model.errorMessage = message
+
+	id := model.errorID
+	return tea.Tick(errorDisplayDuration, func(time.Time) tea.Msg {
+		return ClearErrorMessage(id)
+	})
+}
+
+func (model *Model) handleMouse(msg tea.MouseMsg) tea.Cmd {
+	var cmd tea.Cmd
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		model.cursor = max(model.cursor-1, 0)
+
+	case tea.MouseButtonWheelDown:
+		model.cursor = max(min(model.cursor+1, model.listLength()-1), 0)
+
+	case tea.MouseButtonLeft:
+		if msg.Action != tea.MouseActionPress {
+			return nil
+		}
+
+		row := msg.Y - 1
+		if row < 0 || row >= model.mainContentHeight() {
+			return nil
+		}
+
+		if msg.X >= model.leftPaneWidth()+paneBorderWidth {
+			model.handleStatusPaneClick(msg.X-model.leftPaneWidth()-paneBorderWidth-1, row)
+			return nil
+		}
+
+		rows, _ := model.listRows()
+		if model.offset+row >= len(rows) {
+			return nil
+		}
+
+		index := rows[model.offset+row].index
+		if index < 0 {
+			return nil
+		}
+
+		if index == model.cursor {
+			cmd = model.playSelected()
+		} else {
+			model.cursor = index
+		}
+	}
+
+	model.scrollToCursor()
+
+	return cmd
+}
+
+func (model *Model) mprisSeek(seconds float64, absolute bool) {
+	if model.isPlaying == Stopped {
+		return
+	}
+
+	var err error
+	target := seconds
+	if absolute {
+		if model.playerState.Duration > 0 && seconds > model.playerState.Duration {
+			return
+		}
+		err = model.player.SeekAbsolute(seconds)
+	} else {
+		target = model.playerState.Position + seconds
+		err = model.player.SeekRelative(seconds)
+	}
+	if err != nil {
+		log.Printf("Failed to seek: %v", err)
+		return
+	}
+
+	if err := model.mprisServer.Seeked(target); err != nil {
+		log.Printf("Failed to update MPRIS position: %v", err)
+	}
+}
+
+func (model *Model) handleStatusPaneClick(column int, row int) {
+	barWidth := model.rightPaneWidth()
+	if row != progressBarRow || column < 0 || column >= barWidth || model.playerState.Duration <= 0 {
+		return
+	}
+
+	position := float64(column) / float64(barWidth) * model.playerState.Duration
+	if err := model.player.SeekAbsolute(position); err != nil {
+		log.Printf("Failed to seek: %v", err)
+	}
+}
+
+func (model *Model) scrollToCursor() {
+	visible := model.mainContentHeight()
+	rows, _ := model.listRows()
+	cursorRow := model.cursorRow(rows)
+
+	top := cursorRow
+	if top > 0 && rows[top-1].index < 0 {
+		top--
+	}
+
+	if top < model.offset {
+		model.offset = top
+	}
+	if cursorRow >= model.offset+visible {
+		model.offset = cursorRow - visible + 1
+	}
+	model.offset = max(min(model.offset, len(rows)-visible), 0)
+}
+
+func (model *Model) listLength() int {
+	switch model.currentView {
+	case Radios:
+		return len(model.stationIndices())
+	case Queue:
+		return len(model.playerState.Playlist)
+	case History:
+		return model.history.Len()
+	case Favorites:
+		return len(model.favoriteItems())
+	default:
+		return len(model.visible)
+	}
+}
+
+func (model *Model) leftPaneWidth() int {
+	return max(model.width/2-paneBorderWidth, 0)
+}
+
+func (model *Model) rightPaneWidth() int {
+	return max(model.width-model.leftPaneWidth()-2*paneBorderWidth, 0)
+}
+
+// tooSmall reports whether the terminal cannot fit the panes and the footer,
+// in which case View shows only a placeholder.
+func (model *Model) tooSmall() bool {
+	return model.width < minWidth || model.height < model.footerHeight+minContentHeight+paneBorderHeight
+}
+
+// modalOpen reports whether a dialog or prompt owns the input, so clicks and
+// scrolling must not reach the list behind it.
+func (model *Model) modalOpen() bool {
+	return model.showHelp || model.showDevices || model.stationForm || model.quitPrompt || model.restorePrompt
+}
+
+// mainContentHeight uses the footer height measured by the last View or
+// resize, so that moving the cursor does not render the footer again.
+func (model *Model) mainContentHeight() int {
+	return max(model.height-model.footerHeight-paneBorderHeight, minContentHeight)
+}
+
+func applyTheme(theme config.Theme) {
+	name := strings.ToLower(theme.Name)
+	if name == "" {
+		name = defaultTheme
+	}
+
+	base, ok := themes[name]
+	if !ok {
+		log.Printf("Unknown theme %q, using %q", theme.Name, defaultTheme)
+		base = themes[defaultTheme]
+	}
+
+	selectedItemStyle = selectedItemStyle.Foreground(parseColor(theme.Selected, base.Selected))
+	paneStyle = paneStyle.BorderForeground(parseColor(theme.Border, base.Border))
+	accentStyle = accentStyle.Foreground(parseColor(theme.Accent, base.Accent))
+	groupHeaderStyle = groupHeaderStyle.Foreground(parseColor(theme.Accent, base.Accent))
+	matchStyle = matchStyle.Foreground(parseColor(theme.Accent, base.Accent))
+	statusStyles[Playing] = statusStyles[Playing].Foreground(parseColor(theme.Playing, base.Playing))
+	statusStyles[Paused] = statusStyles[Paused].Foreground(parseColor(theme.Paused, base.Paused))
+	statusStyles[Stopped] = statusStyles[Stopped].Foreground(parseColor(theme.Stopped, base.Stopped))
+}
+
+func parseColor(value string, fallback string) lipgloss.Color {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return lipgloss.Color(fallback)
+	}
+
+	if code, ok := colorNames[value]; ok {
+		return lipgloss.Color(code)
+	}
+
+	return lipgloss.Color(value)
 }
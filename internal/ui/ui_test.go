@@ -0,0 +1,249 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sokolawesome/tunecli/internal/config"
+	"github.com/sokolawesome/tunecli/internal/player"
+	"github.com/sokolawesome/tunecli/internal/scanner"
+)
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{1024*1024 - 1, "1.0 MB"},
+		{1024 * 1024, "1.0 MB"},
+		{5*1024*1024 + 512*1024, "5.5 MB"},
+		{1024*1024*1024 - 1, "1.0 GB"},
+		{1024 * 1024 * 1024, "1.0 GB"},
+		{3 * 1024 * 1024 * 1024 * 1024, "3.0 TB"},
+		{2048 * 1024 * 1024 * 1024 * 1024, "2048.0 TB"},
+	}
+
+	for _, test := range tests {
+		if got := formatSize(test.bytes); got != test.want {
+			t.Errorf("formatSize(%d) = %q, want %q", test.bytes, got, test.want)
+		}
+	}
+}
+
+func TestFormatTotalDuration(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{-5, "0h 00m"},
+		{0, "0h 00m"},
+		{59, "0h 00m"},
+		{60, "0h 01m"},
+		{3599, "0h 59m"},
+		{3600, "1h 00m"},
+		{100 * 3600, "100h 00m"},
+	}
+
+	for _, test := range tests {
+		if got := formatTotalDuration(test.seconds); got != test.want {
+			t.Errorf("formatTotalDuration(%g) = %q, want %q", test.seconds, got, test.want)
+		}
+	}
+}
+
+func newTestModel(t *testing.T) *Model {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, ".local", "state"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+
+	model, err := NewModel(&player.Player{}, &config.Config{}, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewModel: %v", err)
+	}
+
+	model.Update(LibraryScannedMessage{})
+	model.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	return model
+}
+
+func TestEmptyViewsIgnoreNavigation(t *testing.T) {
+	model := newTestModel(t)
+
+	for _, view := range []CurrentView{Files, Radios} {
+		model.currentView = view
+		for _, key := range []tea.KeyType{tea.KeyUp, tea.KeyDown, tea.KeyEnter} {
+			model.Update(tea.KeyMsg{Type: key})
+		}
+
+		if model.cursor != 0 {
+			t.Errorf("%s view cursor = %d after navigation, want 0", viewNames[view], model.cursor)
+		}
+		if model.View() == "" {
+			t.Errorf("%s view rendered nothing", viewNames[view])
+		}
+	}
+}
+
+func TestViewTooSmall(t *testing.T) {
+	model := newTestModel(t)
+
+	for height := 1; height <= 11; height++ {
+		model.Update(tea.WindowSizeMsg{Width: 80, Height: height})
+		if view := model.View(); !strings.Contains(view, "Terminal too small") {
+			t.Errorf("height %d rendered %q, want the too small message", height, view)
+		}
+	}
+}
+
+func TestViewFitsHeight(t *testing.T) {
+	model := newTestModel(t)
+
+	for height := 12; height <= 40; height++ {
+		model.Update(tea.WindowSizeMsg{Width: 80, Height: height})
+		view := model.View()
+		if strings.Contains(view, "Terminal too small") {
+			t.Errorf("height %d rendered the too small message", height)
+		}
+		if lines := strings.Count(view, "\n") + 1; lines > height {
+			t.Errorf("height %d rendered %d lines", height, lines)
+		}
+	}
+}
+
+func TestAddStationRollsBackOnSaveFailure(t *testing.T) {
+	model := newTestModel(t)
+	model.config.Path = filepath.Join(t.TempDir(), "missing", "config.yaml")
+
+	for range 2 {
+		model.addStation("Jazz", "https://jazz.example/live")
+		if len(model.stations) != 0 || len(model.config.Stations) != 0 {
+			t.Fatalf("stations after failed save = %+v, want none", model.stations)
+		}
+		if model.errorMessage != "Failed to save station" {
+			t.Errorf("error after failed save = %q, want the save failure", model.errorMessage)
+		}
+	}
+}
+
+func TestViewTruncatesLongNames(t *testing.T) {
+	model := newTestModel(t)
+
+	var songs []scanner.MusicFile
+	for i := range 30 {
+		name := fmt.Sprintf("%02d %s.flac", i, strings.Repeat("a very long track title ", 4))
+		songs = append(songs, scanner.MusicFile{Path: "/music/" + name, Name: name, Format: "FLAC"})
+	}
+	model.Update(LibraryScannedMessage{songs: songs})
+	model.playerState.Title = strings.Repeat("a very long stream title ", 8)
+
+	for _, width := range []int{60, 80, 120} {
+		model.Update(tea.WindowSizeMsg{Width: width, Height: 24})
+		if height := lipgloss.Height(model.View()); height > 24 {
+			t.Errorf("width %d rendered %d lines at height 24", width, height)
+		}
+	}
+}
+
+func TestMouseIgnoredBehindModals(t *testing.T) {
+	model := newTestModel(t)
+
+	var songs []scanner.MusicFile
+	for i := range 5 {
+		name := fmt.Sprintf("%02d.mp3", i)
+		songs = append(songs, scanner.MusicFile{Path: "/music/" + name, Name: name, Format: "MP3"})
+	}
+	model.Update(LibraryScannedMessage{songs: songs})
+
+	wheel := tea.MouseMsg{Button: tea.MouseButtonWheelDown, Action: tea.MouseActionPress}
+	tests := []struct {
+		name string
+		open func()
+	}{
+		{"help", func() { model.showHelp = true }},
+		{"device picker", func() { model.showDevices = true }},
+		{"station form", func() { model.stationForm = true }},
+		{"quit prompt", func() { model.quitPrompt = true }},
+		{"restore prompt", func() { model.restorePrompt = true }},
+		{"too small", func() { model.Update(tea.WindowSizeMsg{Width: 20, Height: 5}) }},
+	}
+
+	for _, test := range tests {
+		model.showHelp, model.showDevices, model.stationForm = false, false, false
+		model.quitPrompt, model.restorePrompt = false, false
+		model.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+		model.cursor = 0
+
+		test.open()
+		model.Update(wheel)
+		if model.cursor != 0 {
+			t.Errorf("%s: wheel moved the cursor to %d", test.name, model.cursor)
+		}
+	}
+
+	model.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	model.Update(wheel)
+	if model.cursor != 1 {
+		t.Errorf("wheel without a modal left the cursor at %d, want 1", model.cursor)
+	}
+}
+
+func TestMoveStationRollsBackOnSaveFailure(t *testing.T) {
+	model := newTestModel(t)
+	stations := []config.Stations{
+		{Name: "Jazz", Url: "https://jazz.example/live"},
+		{Name: "Lo-Fi", Url: "https://lofi.example/live"},
+	}
+	model.stations = slices.Clone(stations)
+	model.config.Stations = model.stations
+	model.config.Path = filepath.Join(t.TempDir(), "missing", "config.yaml")
+	model.currentView = Radios
+	model.cursor = 0
+
+	model.moveSelected(1)
+	if !slices.Equal(model.stations, stations) || !slices.Equal(model.config.Stations, stations) {
+		t.Errorf("stations after failed save = %+v, want the original order", model.stations)
+	}
+	if model.cursor != 0 {
+		t.Errorf("cursor after failed save = %d, want 0", model.cursor)
+	}
+}
+
+func TestViewFitsAfterFooterGrows(t *testing.T) {
+	model := newTestModel(t)
+
+	var songs []scanner.MusicFile
+	for i := range 30 {
+		name := fmt.Sprintf("%02d.mp3", i)
+		songs = append(songs, scanner.MusicFile{Path: "/music/" + name, Name: name, Format: "MP3"})
+	}
+	model.Update(LibraryScannedMessage{songs: songs})
+	model.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	model.View()
+
+	model.cursor = len(songs) - 1
+	model.scrollToCursor()
+	model.showError("Failed to save station order")
+	model.logs = append(model.logs, "first log line", "second log line")
+
+	view := model.View()
+	if height := lipgloss.Height(view); height > 24 {
+		t.Errorf("view after the footer grew rendered %d lines at height 24", height)
+	}
+	if !strings.Contains(view, "> "+songs[len(songs)-1].Name) {
+		t.Error("selected song scrolled out of view after the footer grew")
+	}
+}
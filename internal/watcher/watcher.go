@@ -0,0 +1,169 @@
+package watcher
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sokolawesome/tunecli/internal/scanner"
+)
+
+const debounceDelay = 500 * time.Millisecond
+
+type Watcher struct {
+	Changes chan struct{}
+	watcher *fsnotify.Watcher
+	options scanner.Options
+	shallow map[string]bool
+}
+
+func NewWatcher(dirs []scanner.Directory, options scanner.Options) (*Watcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %s", err)
+	}
+
+	watcher := &Watcher{
+		Changes: make(chan struct{}, 1),
+		watcher: fsWatcher,
+		options: options,
+		shallow: make(map[string]bool),
+	}
+
+	for _, dir := range dirs {
+		if dir.Recursive {
+			watcher.addTree(dir.Path)
+			continue
+		}
+
+		watcher.shallow[filepath.Clean(dir.Path)] = true
+		if err := fsWatcher.Add(dir.Path); err != nil {
+			log.Printf("Failed to watch %s: %v", dir.Path, err)
+		}
+	}
+
+	go watcher.run()
+
+	return watcher, nil
+}
+
+func (watcher *Watcher) addTree(root string) {
+	watcher.walkTree(root, make(map[string]bool))
+}
+
+// walkTree watches every directory below root. With follow_symlinks it also
+// descends into symlinked directories, skipping real paths already in visited
+// so that symlink loops end like they do in the scanner.
+func (watcher *Watcher) walkTree(root string, visited map[string]bool) {
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path != root && watcher.options.Excluded(path, entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if watcher.options.FollowSymlinks && entry.Type()&fs.ModeSymlink != 0 {
+			watcher.followSymlink(path, visited)
+			return nil
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if watcher.options.FollowSymlinks {
+			realPath, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				log.Printf("Failed to resolve %s: %v", path, err)
+				return filepath.SkipDir
+			}
+			if visited[realPath] {
+				return filepath.SkipDir
+			}
+			visited[realPath] = true
+		}
+
+		if err := watcher.watcher.Add(path); err != nil {
+			log.Printf("Failed to watch %s: %v", path, err)
+		}
+		return nil
+	})
+}
+
+func (watcher *Watcher) followSymlink(path string, visited map[string]bool) {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		log.Printf("Failed to resolve symlink %s: %v", path, err)
+		return
+	}
+
+	info, err := os.Stat(realPath)
+	if err != nil || !info.IsDir() {
+		return
+	}
+
+	watcher.walkTree(realPath, visited)
+}
+
+func (watcher *Watcher) run() {
+	timer := time.NewTimer(debounceDelay)
+	timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.watcher.Events:
+			if !ok {
+				return
+			}
+			if !watcher.relevant(event) {
+				continue
+			}
+			timer.Reset(debounceDelay)
+
+		case err, ok := <-watcher.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Watcher error: %v", err)
+
+		case <-timer.C:
+			select {
+			case watcher.Changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (watcher *Watcher) relevant(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	if watcher.options.Excluded(event.Name, filepath.Base(event.Name)) {
+		return false
+	}
+
+	if event.Has(fsnotify.Create) {
+		info, err := os.Stat(event.Name)
+		if err == nil && info.IsDir() {
+			if watcher.shallow[filepath.Dir(event.Name)] {
+				return false
+			}
+			watcher.addTree(event.Name)
+			return true
+		}
+	}
+
+	return scanner.IsAudioFile(event.Name) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+}
+
+func (watcher *Watcher) Close() error {
+	return watcher.watcher.Close()
+}
@@ -0,0 +1,52 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sokolawesome/tunecli/internal/scanner"
+)
+
+func TestAddTreeFollowsSymlinks(t *testing.T) {
+	root, target := t.TempDir(), t.TempDir()
+	if err := os.Mkdir(filepath.Join(target, "album"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, filepath.Join(root, "linked")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root, filepath.Join(target, "album", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	realTarget, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		follow bool
+		want   bool
+	}{
+		{false, false},
+		{true, true},
+	}
+
+	for _, test := range tests {
+		dirs := []scanner.Directory{{Path: root, Recursive: true}}
+		watcher, err := NewWatcher(dirs, scanner.Options{FollowSymlinks: test.follow})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		watched := watcher.watcher.WatchList()
+		watcher.Close()
+
+		album := filepath.Join(realTarget, "album")
+		if got := slices.Contains(watched, album); got != test.want {
+			t.Errorf("follow %t: watching %s = %t, want %t (watched %v)", test.follow, album, got, test.want, watched)
+		}
+	}
+}